@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mcp/mcpclient"
+	"sync"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server/session"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+	"github.com/google/uuid"
+)
+
+// Bridge forwards the JSON-RPC messages of every local session to its own connection to the remote server.
+// Messages are not interpreted beyond what routing needs, so every request, response and notification,
+// including progress and cancellation, passes through unchanged.
+type Bridge struct {
+	Remote mcpclient.Config
+	Logger pkg.Logger
+	// MaxBackoff bounds the delay between two reconnection attempts
+	MaxBackoff time.Duration
+	// IdleTimeout closes HTTP sessions without messages for that long, 0 keeps them until the client closes them
+	IdleTimeout time.Duration
+
+	local    transport.ServerTransport
+	sessions *sessions
+
+	mu    sync.Mutex
+	links map[string]*link
+}
+
+// envelope the fields of a JSON-RPC message needed to route it
+type envelope struct {
+	ID     json.RawMessage `json:"id"`
+	Method protocol.Method `json:"method"`
+	Params struct {
+		Meta struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"_meta"`
+		ProgressToken json.RawMessage `json:"progressToken"`
+		RequestID     json.RawMessage `json:"requestId"`
+	} `json:"params"`
+}
+
+// Serve forwards the sessions of the local transport until it stops
+func (b *Bridge) Serve(local transport.ServerTransport) error {
+	b.local = local
+	b.links = make(map[string]*link)
+	b.sessions = &sessions{
+		Manager: session.NewManager(func(context.Context, string) error { return nil },
+			func(context.Context) string { return uuid.NewString() }),
+		onClose: b.closeLink,
+	}
+	b.sessions.SetLogger(b.Logger)
+	if b.IdleTimeout > 0 {
+		b.sessions.SetMaxIdleTime(b.IdleTimeout)
+		go b.sessions.StartHeartbeatAndCleanInvalidSessions()
+		defer b.sessions.StopHeartbeat()
+	}
+
+	local.SetReceiver(transport.ServerReceiverF(b.receive))
+	local.SetSessionManager(b.sessions)
+	return local.Run()
+}
+
+// Shutdown stops the local transport and closes every remote connection
+func (b *Bridge) Shutdown(ctx context.Context) error {
+	// The local transport waits for the end of the in-flight messages, which the bridge does not track
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := b.local.Shutdown(ctx, done)
+
+	b.mu.Lock()
+	links := b.links
+	b.links = make(map[string]*link)
+	b.mu.Unlock()
+	for _, l := range links {
+		l.close()
+	}
+	return err
+}
+
+// receive handles a message of a local client. Requests get a channel carrying their response
+// and the progress notifications sent for them, notifications and responses get none.
+func (b *Bridge) receive(ctx context.Context, sessionID string, msg []byte) (<-chan []byte, error) {
+	var m envelope
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return nil, pkg.ErrJSONUnmarshal
+	}
+
+	// The streamable HTTP transport expects the session ID of an initialize request to be returned
+	if ret, ok := ctx.Value(transport.SessionIDForReturnKey{}).(*transport.SessionIDForReturn); ok && m.Method == protocol.Initialize {
+		sessionID = b.sessions.CreateSession(ctx)
+		ret.SessionID = sessionID
+	}
+	if sessionID != "" && !b.sessions.IsActiveSession(sessionID) {
+		if b.sessions.IsClosedSession(sessionID) {
+			return nil, pkg.ErrSessionClosed
+		}
+		return nil, pkg.ErrLackSession
+	}
+	b.sessions.UpdateSessionLastActiveAt(sessionID)
+
+	l := b.link(sessionID)
+	switch {
+	case len(m.ID) > 0 && m.Method != "":
+		if m.Method == protocol.Initialize {
+			l.setInitialize(msg)
+		}
+		return l.sendRequest(ctx, string(m.ID), string(m.Params.Meta.ProgressToken), msg), nil
+	case m.Method != "":
+		switch m.Method {
+		case protocol.NotificationInitialized:
+			l.setInitialized()
+		case protocol.NotificationCancelled:
+			// The remote server sends no response to a cancelled request
+			l.cancel(string(m.Params.RequestID))
+		}
+		if err := l.send(ctx, msg); err != nil {
+			slog.WarnContext(ctx, "Failed to forward notification", "method", m.Method, "error", err)
+		}
+		return nil, nil
+	default:
+		if err := l.send(ctx, msg); err != nil {
+			slog.WarnContext(ctx, "Failed to forward response", "id", string(m.ID), "error", err)
+		}
+		return nil, nil
+	}
+}
+
+// link returns the remote connection of a local session, connecting it on first use
+func (b *Bridge) link(sessionID string) *link {
+	b.mu.Lock()
+	l, ok := b.links[sessionID]
+	if !ok {
+		l = newLink(b, sessionID)
+		b.links[sessionID] = l
+	}
+	b.mu.Unlock()
+
+	l.start()
+	return l
+}
+
+func (b *Bridge) closeLink(sessionID string) {
+	b.mu.Lock()
+	l, ok := b.links[sessionID]
+	delete(b.links, sessionID)
+	b.mu.Unlock()
+	if ok {
+		slog.Info("Local session closed", "session_id", sessionID)
+		l.close()
+	}
+}
+
+// deliver sends a message of the remote server to the local client outside of any request stream
+func (b *Bridge) deliver(sessionID string, msg []byte) {
+	if err := b.local.Send(context.Background(), sessionID, msg); err != nil {
+		if errors.Is(err, session.ErrQueueNotOpened) {
+			slog.Debug("Dropped remote message, the client has no open stream", "session_id", sessionID)
+			return
+		}
+		slog.Warn("Failed to deliver remote message", "session_id", sessionID, "error", err)
+	}
+}
+
+// sessions is the SDK session manager, telling the bridge when a local session ends
+type sessions struct {
+	*session.Manager
+	onClose func(sessionID string)
+}
+
+func (s *sessions) CloseSession(sessionID string) {
+	s.Manager.CloseSession(sessionID)
+	s.onClose(sessionID)
+}
+
+func (s *sessions) CloseAllSessions() {
+	s.RangeSessions(func(sessionID string, _ *session.State) bool {
+		s.CloseSession(sessionID)
+		return true
+	})
+}
+
+// errorResponse builds the JSON-RPC error answering the request id
+func errorResponse(id string, message string) []byte {
+	resp, _ := json.Marshal(protocol.NewJSONRPCErrorResponse(json.RawMessage(id), protocol.InternalError, message))
+	return resp
+}
@@ -0,0 +1 @@
+go build -v -o mcpBridge .
\ No newline at end of file
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mcp/mcpclient"
+	"sync"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+)
+
+// reinitID is the request ID of the initialize request replayed after a reconnect, its response is not forwarded
+const reinitID = `"bridge-reinit"`
+
+// reinitTimeout bounds the wait for the response to a replayed initialize request
+const reinitTimeout = 30 * time.Second
+
+// retryTimeout bounds the wait for a reconnection before a request that hit a dropped connection is sent again
+const retryTimeout = 10 * time.Second
+
+var (
+	errUnavailable = errors.New("not connected, reconnecting")
+	errLinkClosed  = errors.New("link closed")
+)
+
+// link the connection of one local session to the remote server
+type link struct {
+	bridge    *Bridge
+	sessionID string
+	startOnce sync.Once
+
+	mu sync.Mutex
+	// transport is nil while disconnected, connected is closed once it is set
+	transport transport.ClientTransport
+	connected chan struct{}
+	// pending requests of the local client by JSON-RPC ID
+	pending map[string]*pendingRequest
+	// initialize is the initialize request of the local client, replayed after a reconnect
+	initialize  []byte
+	initialized bool
+	reinit      chan []byte
+	closed      bool
+}
+
+// pendingRequest a forwarded request waiting for its response
+type pendingRequest struct {
+	ch            chan []byte
+	progressToken string
+}
+
+func newLink(b *Bridge, sessionID string) *link {
+	return &link{
+		bridge:    b,
+		sessionID: sessionID,
+		connected: make(chan struct{}),
+		pending:   make(map[string]*pendingRequest),
+		reinit:    make(chan []byte, 1),
+	}
+}
+
+// start connects the link once, a failed first attempt keeps reconnecting in the background
+func (l *link) start() {
+	l.startOnce.Do(func() {
+		t, err := l.connect()
+		if err == nil {
+			err = l.publish(t)
+		}
+		if err != nil {
+			if errors.Is(err, errLinkClosed) {
+				return
+			}
+			slog.Warn("Failed to connect to remote server", "session_id", l.sessionID, "error", err)
+			go l.reconnect()
+			return
+		}
+		slog.Info("Connected to remote server", "session_id", l.sessionID, "transport", l.bridge.Remote.Transport)
+	})
+}
+
+// connect starts a new transport to the remote server, it is not used for local messages before publish
+func (l *link) connect() (transport.ClientTransport, error) {
+	var t transport.ClientTransport
+	cfg := l.bridge.Remote
+	cfg.StreamError = func(err error) {
+		l.lost(t, err)
+	}
+	t, err := mcpclient.NewTransport(cfg, l.bridge.Logger)
+	if err != nil {
+		return nil, err
+	}
+	t.SetReceiver(transport.NewClientReceiver(
+		func(ctx context.Context, msg []byte) error {
+			l.receive(msg)
+			return nil
+		},
+		func(err error) {
+			l.lost(t, err)
+		},
+	))
+	if err = t.Start(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (l *link) publish(t transport.ClientTransport) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		go t.Close()
+		return errLinkClosed
+	}
+	l.transport = t
+	close(l.connected)
+	return nil
+}
+
+// reconnect connects again with exponential backoff and replays the initialization of the local client,
+// so that the remote server sees the same session as before the drop
+func (l *link) reconnect() {
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		time.Sleep(delay)
+		if delay *= 2; delay > l.bridge.MaxBackoff {
+			delay = l.bridge.MaxBackoff
+		}
+		if l.isClosed() {
+			return
+		}
+
+		t, err := l.connect()
+		if err == nil {
+			if err = l.replay(t); err != nil {
+				go t.Close()
+			}
+		}
+		if err == nil {
+			if err = l.publish(t); errors.Is(err, errLinkClosed) {
+				return
+			}
+		}
+		if err != nil {
+			slog.Warn("Failed to reconnect to remote server", "session_id", l.sessionID, "attempt", attempt, "error", err)
+			continue
+		}
+		slog.Info("Reconnected to remote server", "session_id", l.sessionID, "attempt", attempt)
+		return
+	}
+}
+
+// replay sends the initialize request and initialized notification of the local client again
+func (l *link) replay(t transport.ClientTransport) error {
+	l.mu.Lock()
+	initialize, initialized := l.initialize, l.initialized
+	l.mu.Unlock()
+	if initialize == nil {
+		return nil
+	}
+
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(initialize, &req); err != nil {
+		return err
+	}
+	req["id"] = json.RawMessage(reinitID)
+	msg, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reinitTimeout)
+	defer cancel()
+	if err = t.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send initialize request: %v", err)
+	}
+	select {
+	case resp := <-l.reinit:
+		var result struct {
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err = json.Unmarshal(resp, &result); err != nil {
+			return err
+		}
+		if result.Error != nil {
+			return fmt.Errorf("initialize failed: %s", result.Error.Message)
+		}
+	case <-ctx.Done():
+		return fmt.Errorf("no response to initialize request")
+	}
+
+	if !initialized {
+		return nil
+	}
+	notify, err := json.Marshal(protocol.NewJSONRPCNotification(protocol.NotificationInitialized, protocol.NewInitializedNotification()))
+	if err != nil {
+		return err
+	}
+	return t.Send(ctx, notify)
+}
+
+// send forwards a message of the local client, a failure counts as a dropped connection
+func (l *link) send(ctx context.Context, msg []byte) error {
+	l.mu.Lock()
+	t := l.transport
+	l.mu.Unlock()
+	if t == nil {
+		return errUnavailable
+	}
+
+	if err := t.Send(ctx, msg); err != nil {
+		l.lost(t, err)
+		return err
+	}
+	return nil
+}
+
+// sendRequest forwards a request of the local client and returns the channel of its response.
+// A request that hits a dropped connection is sent again once the link reconnected.
+func (l *link) sendRequest(ctx context.Context, id, progressToken string, msg []byte) <-chan []byte {
+	ch := l.addPending(id, progressToken)
+	err := l.send(ctx, msg)
+	if err != nil && l.awaitReconnect(ctx) {
+		// The failed attempt was answered when the connection was declared lost
+		ch = l.addPending(id, progressToken)
+		err = l.send(ctx, msg)
+	}
+	if err != nil {
+		l.fail(id, "remote server unavailable: "+err.Error())
+	}
+	return ch
+}
+
+// awaitReconnect waits until the link is connected again, false when it is closed or the wait times out
+func (l *link) awaitReconnect(ctx context.Context) bool {
+	l.mu.Lock()
+	connected, closed := l.connected, l.closed
+	l.mu.Unlock()
+	if closed {
+		return false
+	}
+
+	timer := time.NewTimer(retryTimeout)
+	defer timer.Stop()
+	select {
+	case <-connected:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+// receive routes a message of the remote server: responses and progress go to the stream of their request,
+// everything else to the session of the local client
+func (l *link) receive(msg []byte) {
+	var m envelope
+	if err := json.Unmarshal(msg, &m); err != nil {
+		slog.Warn("Dropped invalid remote message", "session_id", l.sessionID, "error", err)
+		return
+	}
+
+	switch {
+	case len(m.ID) > 0 && m.Method == "":
+		id := string(m.ID)
+		if id == reinitID {
+			select {
+			case l.reinit <- msg:
+			default:
+			}
+			return
+		}
+
+		l.mu.Lock()
+		p, ok := l.pending[id]
+		delete(l.pending, id)
+		l.mu.Unlock()
+		if !ok {
+			slog.Debug("Dropped response to an unknown or cancelled request", "session_id", l.sessionID, "id", id)
+			return
+		}
+		p.ch <- msg
+		close(p.ch)
+		return
+	case m.Method == protocol.NotificationProgress:
+		if l.progress(string(m.Params.ProgressToken), msg) {
+			return
+		}
+	}
+	l.bridge.deliver(l.sessionID, msg)
+}
+
+// progress passes a progress notification to the stream of the request it belongs to
+func (l *link) progress(token string, msg []byte) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, p := range l.pending {
+		if p.progressToken == "" || p.progressToken != token {
+			continue
+		}
+		select {
+		case p.ch <- msg:
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// lost handles a dropped connection: pending requests fail and the link reconnects.
+// Drops of transports already replaced or closed are ignored.
+func (l *link) lost(t transport.ClientTransport, err error) {
+	l.mu.Lock()
+	if l.closed || l.transport != t {
+		l.mu.Unlock()
+		return
+	}
+	l.transport = nil
+	l.connected = make(chan struct{})
+	pending := l.pending
+	l.pending = make(map[string]*pendingRequest)
+	l.mu.Unlock()
+
+	slog.Warn("Remote server connection lost", "session_id", l.sessionID, "error", err)
+	// The transport may be reporting from its own goroutine, which Close waits for
+	go t.Close()
+	failAll(pending, "remote server connection lost")
+	go l.reconnect()
+}
+
+func (l *link) setInitialize(msg []byte) {
+	l.mu.Lock()
+	l.initialize = msg
+	l.initialized = false
+	l.mu.Unlock()
+}
+
+func (l *link) setInitialized() {
+	l.mu.Lock()
+	l.initialized = true
+	l.mu.Unlock()
+}
+
+func (l *link) addPending(id, progressToken string) chan []byte {
+	// Progress notifications are buffered, the response must always fit
+	ch := make(chan []byte, 16)
+	l.mu.Lock()
+	l.pending[id] = &pendingRequest{ch: ch, progressToken: progressToken}
+	l.mu.Unlock()
+	return ch
+}
+
+// fail answers a pending request with an error
+func (l *link) fail(id, message string) {
+	l.mu.Lock()
+	p, ok := l.pending[id]
+	delete(l.pending, id)
+	l.mu.Unlock()
+	if ok {
+		p.ch <- errorResponse(id, message)
+		close(p.ch)
+	}
+}
+
+// cancel ends the stream of a cancelled request without a response
+func (l *link) cancel(id string) {
+	l.mu.Lock()
+	p, ok := l.pending[id]
+	delete(l.pending, id)
+	l.mu.Unlock()
+	if ok {
+		close(p.ch)
+	}
+}
+
+func (l *link) isClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed
+}
+
+// close disconnects the link for good
+func (l *link) close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	t := l.transport
+	l.transport = nil
+	pending := l.pending
+	l.pending = make(map[string]*pendingRequest)
+	l.mu.Unlock()
+
+	failAll(pending, "bridge session closed")
+	if t != nil {
+		if err := t.Close(); err != nil {
+			slog.Debug("Failed to close remote transport", "session_id", l.sessionID, "error", err)
+		}
+	}
+}
+
+func failAll(pending map[string]*pendingRequest, message string) {
+	for id, p := range pending {
+		p.ch <- errorResponse(id, message)
+		close(p.ch)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mcp/logging"
+	"mcp/mcpclient"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+)
+
+// remote an MCP server over HTTP that can be stopped and started again on the same address
+type remote struct {
+	t         *testing.T
+	transport string
+	addr      string
+
+	httpServer *httptest.Server
+	mcpServer  *server.Server
+	// started receives a value when the wait tool is called, release ends the call
+	started chan struct{}
+	release chan struct{}
+}
+
+func newRemote(t *testing.T, transportName string) *remote {
+	r := &remote{t: t, transport: transportName, addr: "127.0.0.1:0"}
+	r.start()
+	t.Cleanup(r.stop)
+	return r
+}
+
+// url returns the endpoint the bridge connects to
+func (r *remote) url() string {
+	if r.transport == mcpclient.SSE {
+		return "http://" + r.addr + "/sse"
+	}
+	return "http://" + r.addr + "/mcp"
+}
+
+// start serves a new MCP server, with none of the sessions of the previous one
+func (r *remote) start() {
+	t := r.t
+	t.Helper()
+	logger := logging.SDKLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mux := http.NewServeMux()
+	var (
+		transportServer transport.ServerTransport
+		err             error
+	)
+	if r.transport == mcpclient.SSE {
+		var handler *transport.SSEHandler
+		transportServer, handler, err = transport.NewSSEServerTransportAndHandler("/message",
+			transport.WithSSEServerTransportAndHandlerOptionLogger(logger))
+		if err == nil {
+			mux.Handle("/sse", handler.HandleSSE())
+			mux.Handle("/message", handler.HandleMessage())
+		}
+	} else {
+		var handler *transport.StreamableHTTPHandler
+		transportServer, handler, err = transport.NewStreamableHTTPServerTransportAndHandler(
+			transport.WithStreamableHTTPServerTransportAndHandlerOptionStateMode(transport.Stateful),
+			transport.WithStreamableHTTPServerTransportAndHandlerOptionLogger(logger))
+		if err == nil {
+			mux.Handle("/mcp", handler.HandleMCP())
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.mcpServer, err = server.NewServer(transportServer,
+		server.WithServerInfo(protocol.Implementation{Name: "remote", Version: "test"}),
+		server.WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.started = make(chan struct{}, 1)
+	r.release = make(chan struct{})
+	started, release := r.started, r.release
+	r.addTool("wait", func(ctx context.Context) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+	})
+	r.addTool("echo", func(context.Context) {})
+	go r.mcpServer.Run()
+
+	listener, err := net.Listen("tcp", r.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.addr = listener.Addr().String()
+	r.httpServer = httptest.NewUnstartedServer(mux)
+	r.httpServer.Listener = listener
+	r.httpServer.Start()
+}
+
+// addTool registers a tool that runs do and answers with its name
+func (r *remote) addTool(name string, do func(ctx context.Context)) {
+	tool, err := protocol.NewTool(name, name, struct{}{})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	r.mcpServer.RegisterTool(tool, func(ctx context.Context, _ *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		do(ctx)
+		return &protocol.CallToolResult{Content: []protocol.Content{&protocol.TextContent{Type: "text", Text: name}}}, nil
+	})
+}
+
+// stop drops every connection of the remote server, as a crash or restart would
+func (r *remote) stop() {
+	if r.httpServer == nil {
+		return
+	}
+	r.httpServer.CloseClientConnections()
+	close(r.release)
+	r.httpServer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	r.mcpServer.Shutdown(ctx)
+	r.httpServer = nil
+}
+
+// request sends a request of the local client over the link
+func request(l *link, id int, method string, params any) <-chan []byte {
+	msg, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if method == string(protocol.Initialize) {
+		l.setInitialize(msg)
+	}
+	return l.sendRequest(context.Background(), fmt.Sprint(id), "", msg)
+}
+
+// response waits for the response of a request and returns its result, or the message of its error
+func response(t *testing.T, ch <-chan []byte, timeout time.Duration) (json.RawMessage, string) {
+	t.Helper()
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			t.Fatal("request stream closed without a response")
+		}
+		var resp struct {
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			t.Fatalf("invalid response %s: %v", msg, err)
+		}
+		if resp.Error != nil {
+			return nil, resp.Error.Message
+		}
+		return resp.Result, ""
+	case <-time.After(timeout):
+		t.Fatalf("no response within %v", timeout)
+	}
+	return nil, ""
+}
+
+func TestLinkReconnectsAfterRemoteRestart(t *testing.T) {
+	for _, transportName := range []string{mcpclient.SSE, mcpclient.Streamable} {
+		t.Run(transportName, func(t *testing.T) {
+			remote := newRemote(t, transportName)
+			b := &Bridge{
+				Remote:     mcpclient.Config{Transport: transportName, URL: remote.url()},
+				Logger:     logging.SDKLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+				MaxBackoff: time.Second,
+			}
+			l := newLink(b, "local")
+			l.start()
+			defer l.close()
+
+			if _, errMsg := response(t, request(l, 1, string(protocol.Initialize), protocol.InitializeRequest{
+				ProtocolVersion: protocol.Version,
+				ClientInfo:      &protocol.Implementation{Name: "local", Version: "test"},
+			}), 5*time.Second); errMsg != "" {
+				t.Fatalf("initialize failed: %s", errMsg)
+			}
+			initialized, _ := json.Marshal(protocol.NewJSONRPCNotification(protocol.NotificationInitialized, protocol.NewInitializedNotification()))
+			l.setInitialized()
+			if err := l.send(context.Background(), initialized); err != nil {
+				t.Fatal(err)
+			}
+
+			// A call in flight when the remote server goes away fails instead of hanging
+			pending := request(l, 2, string(protocol.ToolsCall), map[string]any{"name": "wait", "arguments": map[string]any{}})
+			select {
+			case <-remote.started:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the wait tool was not called")
+			}
+			remote.stop()
+			if _, errMsg := response(t, pending, 5*time.Second); !strings.Contains(errMsg, "connection lost") {
+				t.Fatalf("got error %q for the pending call, want a lost connection", errMsg)
+			}
+
+			// The link connects to the restarted server and replays the initialization
+			remote.start()
+			result, errMsg := response(t, request(l, 3, string(protocol.ToolsCall), map[string]any{"name": "echo", "arguments": map[string]any{}}), 15*time.Second)
+			if errMsg != "" {
+				t.Fatalf("call after the restart failed: %s", errMsg)
+			}
+			if !strings.Contains(string(result), `"echo"`) {
+				t.Fatalf("got result %s, want the echo tool answer", result)
+			}
+		})
+	}
+}
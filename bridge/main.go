@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"mcp/logging"
+	"mcp/mcpclient"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+)
+
+const usage = `Usage: mcpBridge [flags] [-- command args...]
+
+Exposes a remote MCP server over a local transport, e.g. a remote SSE server as a local stdio server:
+
+  mcpBridge -remote sse -url "https://mcp.amap.com/sse?key=..."
+
+or a local stdio server over streamable HTTP:
+
+  mcpBridge -local streamable -addr 127.0.0.1:8091 -remote stdio -- ./miniMaxMCPServer
+
+Flags:
+`
+
+func main() {
+	os.Exit(run())
+}
+
+// run starts the bridge and returns the exit code once it stopped, so that the remote
+// connection is shut down on every exit
+func run() int {
+	var headers []string
+	flags := flag.NewFlagSet("mcpBridge", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	local := flags.String("local", mcpclient.Stdio, "local transport: stdio, sse (/sse and /message) or streamable (/mcp)")
+	addr := flags.String("addr", "127.0.0.1:8091", "listen address of the sse and streamable local transports")
+	remote := flags.String("remote", mcpclient.Streamable, "remote transport: stdio, sse or streamable")
+	url := flags.String("url", "", "URL of the remote sse or streamable endpoint")
+	flags.Func("header", `HTTP header sent to the remote server, "Key: Value", repeatable`, func(value string) error {
+		headers = append(headers, value)
+		return nil
+	})
+	timeout := flags.Duration("timeout", 0, "timeout of each remote request, 0 keeps the SDK default")
+	maxBackoff := flags.Duration("max-backoff", 30*time.Second, "maximum delay between reconnection attempts")
+	idleTimeout := flags.Duration("idle-timeout", 30*time.Minute, "close sse and streamable sessions idle for that long, 0 never does")
+	logLevel := flags.String("log-level", "info", "debug, info, warn or error, logs go to stderr")
+	_ = flags.Parse(os.Args[1:])
+
+	// Structured logging, stdout is reserved for MCP messages in stdio mode
+	logger, _, err := logging.New(logging.Config{Level: *logLevel, Stdio: *local == mcpclient.Stdio})
+	if err != nil {
+		return failed("Failed to set up logging", "error", err)
+	}
+	slog.SetDefault(logger)
+	sdkLogger := logging.SDKLogger(logger)
+
+	header, err := mcpclient.ParseHeaders(headers)
+	if err != nil {
+		return failed("Invalid header", "error", err)
+	}
+	remoteCfg := mcpclient.Config{
+		Transport: *remote,
+		URL:       *url,
+		Header:    header,
+		Timeout:   *timeout,
+	}
+	if args := flags.Args(); len(args) > 0 {
+		remoteCfg.Command, remoteCfg.Args = args[0], args[1:]
+	}
+	if err = remoteCfg.Validate(); err != nil {
+		return failed("Invalid remote server", "error", err)
+	}
+
+	// Create the local transport layer
+	var (
+		transportServer transport.ServerTransport
+		httpServer      *http.Server
+		serveErr        = make(chan error, 1)
+	)
+	switch *local {
+	case mcpclient.SSE:
+		var handler *transport.SSEHandler
+		transportServer, handler, err = transport.NewSSEServerTransportAndHandler("/message",
+			transport.WithSSEServerTransportAndHandlerOptionLogger(sdkLogger))
+		if err != nil {
+			return failed("Failed to create SSE transport", "error", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/sse", handler.HandleSSE())
+		mux.Handle("/message", handler.HandleMessage())
+		httpServer = serveHTTP(*addr, mux, serveErr)
+	case mcpclient.Streamable:
+		var handler *transport.StreamableHTTPHandler
+		transportServer, handler, err = transport.NewStreamableHTTPServerTransportAndHandler(
+			transport.WithStreamableHTTPServerTransportAndHandlerOptionStateMode(transport.Stateful),
+			transport.WithStreamableHTTPServerTransportAndHandlerOptionLogger(sdkLogger))
+		if err != nil {
+			return failed("Failed to create streamable HTTP transport", "error", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", handler.HandleMCP())
+		httpServer = serveHTTP(*addr, mux, serveErr)
+	case mcpclient.Stdio:
+		transportServer = transport.NewStdioServerTransport(transport.WithStdioServerOptionLogger(sdkLogger))
+	default:
+		return failed("Unknown local transport, expected stdio, sse or streamable", "local", *local)
+	}
+
+	bridge := &Bridge{
+		Remote:     remoteCfg,
+		Logger:     sdkLogger,
+		MaxBackoff: *maxBackoff,
+	}
+	// A stdio client is a single session for the lifetime of the process
+	if *local != mcpclient.Stdio {
+		bridge.IdleTimeout = *idleTimeout
+	}
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	slog.Info("Starting bridge", "local", *local, "remote", *remote)
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- bridge.Serve(transportServer)
+	}()
+
+	// A stdio transport stops by itself when the client closes stdin
+	exitCode := 0
+	select {
+	case err = <-runErr:
+		if err != nil {
+			slog.Error("Local transport failed", "error", err)
+			exitCode = 1
+		}
+	case err = <-serveErr:
+		slog.Error("HTTP server failed, shutting down", "error", err)
+		exitCode = 1
+	case <-signalCtx.Done():
+	}
+	stopSignals()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	if err = bridge.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Failed to shut down local transport", "error", err)
+	}
+	if httpServer != nil {
+		if err = httpServer.Shutdown(shutdownCtx); err != nil {
+			httpServer.Close()
+		}
+	}
+	slog.Info("Bridge stopped")
+	return exitCode
+}
+
+// serveHTTP starts serving handler on addr, the failure of the listener is sent to errs
+func serveHTTP(addr string, handler http.Handler, errs chan<- error) *http.Server {
+	slog.Info("Starting HTTP server", "addr", addr)
+	httpServer := &http.Server{
+		Addr:        addr,
+		Handler:     handler,
+		IdleTimeout: time.Minute,
+	}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("listening on %s: %v", addr, err)
+		}
+	}()
+	return httpServer
+}
+
+// failed logs an error and returns the exit code of a failed start
+func failed(msg string, args ...any) int {
+	slog.Error(msg, args...)
+	return 1
+}
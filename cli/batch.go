@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mcp/mcpclient"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of a batch job
+const (
+	statusOK = "ok"
+	// statusToolError the tool ran and returned an error result
+	statusToolError = "tool_error"
+	// statusFailed the call failed, e.g. the connection dropped, it is run again on resume
+	statusFailed = "failed"
+	// statusInvalid the job line could not be parsed, it is run again on resume
+	statusInvalid = "invalid"
+)
+
+// job a line of the jobs file
+type job struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+
+	line int
+	// invalid why the line could not be parsed
+	invalid string
+}
+
+// jobResult a line of the results file
+type jobResult struct {
+	// Line is the line of the job in the jobs file, results are written in completion order
+	Line              int                 `json:"line"`
+	Tool              string              `json:"tool,omitempty"`
+	Status            string              `json:"status"`
+	Content           []mcpclient.Content `json:"content,omitempty"`
+	StructuredContent json.RawMessage     `json:"structuredContent,omitempty"`
+	// Files are the items written to the -save-dir directory
+	Files      []mcpclient.SavedFile `json:"files,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	StartedAt  time.Time             `json:"startedAt"`
+	DurationMs int64                 `json:"durationMs"`
+}
+
+// batch runs the tool calls of a JSONL file and writes their results to another
+func batch(a *app, args []string) error {
+	flags := a.commandFlags("batch")
+	resultsPath := flags.String("results", "", "results JSONL file, defaults to the jobs file with .results.jsonl")
+	concurrency := flags.Int("concurrency", 4, "number of calls running at the same time")
+	rate := flags.Float64("rate", 0, "maximum calls started per second, 0 is unlimited")
+	callTimeout := flags.Duration("call-timeout", 0, "timeout of each call, 0 waits for the server")
+	resume := flags.Bool("resume", false, "continue an existing results file, skipping the jobs it completed")
+	saveDir := flags.String("save-dir", "", "write image, audio and resource items to this directory as <tool>_line<n>_<item>.<ext>")
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usagef("batch takes exactly one jobs file")
+	}
+	if *concurrency < 1 {
+		return usagef("-concurrency must be at least 1")
+	}
+	if *rate < 0 {
+		return usagef("-rate must not be negative")
+	}
+	jobsPath := positional[0]
+	if *resultsPath == "" {
+		*resultsPath = trimJSONL(jobsPath) + ".results.jsonl"
+	}
+
+	jobs, err := readJobs(jobsPath)
+	if err != nil {
+		return err
+	}
+	done, err := completedLines(*resultsPath, *resume)
+	if err != nil {
+		return err
+	}
+	results, err := openResults(*resultsPath)
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	var todo []*job
+	for _, j := range jobs {
+		if !done[j.line] {
+			todo = append(todo, j)
+		}
+	}
+	if skipped := len(jobs) - len(todo); skipped > 0 {
+		fmt.Fprintf(os.Stderr, "resuming: %d of %d jobs already completed\n", skipped, len(jobs))
+	}
+	if len(todo) == 0 {
+		return nil
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	r := &batchRun{conn: conn, results: results, callTimeout: *callTimeout, saveDir: *saveDir, counts: make(map[string]int)}
+	notStarted := r.run(ctx, todo, *concurrency, *rate)
+
+	fmt.Fprintf(os.Stderr, "%d jobs: %d ok, %d tool errors, %d failed, %d invalid, %d not started, results in %s\n",
+		len(todo), r.counts[statusOK], r.counts[statusToolError], r.counts[statusFailed], r.counts[statusInvalid], notStarted, *resultsPath)
+	switch {
+	case r.counts[statusFailed] > 0 || r.counts[statusInvalid] > 0 || notStarted > 0:
+		return fmt.Errorf("%d jobs did not complete, run again with -resume to retry them",
+			r.counts[statusFailed]+r.counts[statusInvalid]+notStarted)
+	case r.counts[statusToolError] > 0:
+		return errToolFailed
+	}
+	return nil
+}
+
+// batchRun the calls of a batch and the file their results go to
+type batchRun struct {
+	conn        *mcpclient.Conn
+	callTimeout time.Duration
+	saveDir     string
+
+	mu      sync.Mutex
+	results *os.File
+	counts  map[string]int
+}
+
+// run starts the jobs at most rate per second on concurrency workers, until they are done or ctx ends.
+// It returns the number of jobs never started because ctx ended or the connection closed, they have no result.
+func (r *batchRun) run(ctx context.Context, jobs []*job, concurrency int, rate float64) int {
+	queue := make(chan *job)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				r.record(r.call(ctx, j))
+			}
+		}()
+	}
+
+	var tick <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	dispatched := 0
+dispatch:
+	for i, j := range jobs {
+		if tick != nil && i > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		select {
+		case queue <- j:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		case <-r.conn.Done():
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return len(jobs) - dispatched
+}
+
+func (r *batchRun) call(ctx context.Context, j *job) *jobResult {
+	result := &jobResult{Line: j.line, Tool: j.Tool, StartedAt: time.Now()}
+	if j.invalid != "" {
+		result.Status, result.Error = statusInvalid, j.invalid
+		return result
+	}
+
+	if r.callTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.callTimeout)
+		defer cancel()
+	}
+	res, err := r.conn.CallTool(ctx, j.Tool, j.Arguments, nil)
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	switch {
+	case err != nil:
+		result.Status, result.Error = statusFailed, err.Error()
+	case res.IsError:
+		result.Status = statusToolError
+	default:
+		result.Status = statusOK
+	}
+	if res == nil {
+		return result
+	}
+	result.Content, result.StructuredContent = res.Content, res.StructuredContent
+	if r.saveDir != "" {
+		// A result that could not be saved is called again on resume
+		if result.Files, err = r.conn.Save(ctx, res.Content, r.saveDir, fmt.Sprintf("%s_line%d", j.Tool, j.line)); err != nil {
+			result.Status, result.Error = statusFailed, fmt.Sprintf("failed to save result: %v", err)
+		}
+	}
+	return result
+}
+
+// record appends a result to the results file, one write per line so that a crash loses at most the line being written
+func (r *batchRun) record(result *jobResult) {
+	line, err := json.Marshal(result)
+	if err != nil {
+		line, _ = json.Marshal(&jobResult{Line: result.Line, Tool: result.Tool, Status: statusFailed,
+			Error: "failed to encode result: " + err.Error(), StartedAt: result.StartedAt, DurationMs: result.DurationMs})
+		result.Status = statusFailed
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[result.Status]++
+	if _, err = r.results.Write(append(line, '\n')); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write result of line %d: %v\n", result.Line, err)
+	}
+	message := fmt.Sprintf("line %d: %s %s in %dms", result.Line, result.Tool, result.Status, result.DurationMs)
+	if result.Error != "" {
+		message += ": " + result.Error
+	}
+	fmt.Fprintln(os.Stderr, message)
+}
+
+// readJobs reads the jobs file, blank lines are skipped and unparsable lines become invalid jobs
+func readJobs(path string) ([]*job, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var jobs []*job
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		j := &job{}
+		if err = json.Unmarshal(line, j); err != nil {
+			j = &job{invalid: fmt.Sprintf("invalid job: %v", err)}
+		} else if j.Tool == "" {
+			j.invalid = "job has no tool"
+		} else if args := bytes.TrimSpace(j.Arguments); string(args) == "null" {
+			j.Arguments = nil
+		} else if len(args) > 0 && args[0] != '{' {
+			j.invalid = "arguments must be a JSON object"
+		}
+		j.line = n
+		jobs = append(jobs, j)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read jobs file: %v", err)
+	}
+	return jobs, nil
+}
+
+// completedLines returns the job lines with an ok or tool_error result in the results file.
+// Without resume the results file must not hold results yet, so that a run never mixes with an older one.
+func completedLines(path string, resume bool) (map[int]bool, error) {
+	done := make(map[int]bool)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results file: %v", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return done, nil
+	}
+	if !resume {
+		return nil, usagef("results file %s already exists, pass -resume to continue it or remove it", path)
+	}
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var result jobResult
+		// The last line may have been cut by a crash
+		if json.Unmarshal(line, &result) != nil {
+			continue
+		}
+		if result.Status == statusOK || result.Status == statusToolError {
+			done[result.Line] = true
+		}
+	}
+	return done, nil
+}
+
+// openResults opens the results file for appending, after ending a line cut by a crash
+func openResults(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if size := info.Size(); size > 0 {
+		last := make([]byte, 1)
+		if _, err = file.ReadAt(last, size-1); err == nil && last[0] != '\n' {
+			_, err = file.Write([]byte("\n"))
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to prepare results file: %v", err)
+		}
+	}
+	return file, nil
+}
+
+// trimJSONL removes the extension of a jobs file
+func trimJSONL(path string) string {
+	for _, ext := range []string{".jsonl", ".json"} {
+		if trimmed, ok := strings.CutSuffix(path, ext); ok && trimmed != "" {
+			return trimmed
+		}
+	}
+	return path
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to a file of a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadJobs(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		// want lists the jobs as line:tool:arguments, or line:!invalid
+		want []string
+	}{
+		{"empty", "", nil},
+		{"arguments", `{"tool": "echo", "arguments": {"text": "hi"}}`, []string{`1:echo:{"text": "hi"}`}},
+		{"no arguments", `{"tool": "echo"}`, []string{"1:echo:"}},
+		{"null arguments", `{"tool": "echo", "arguments": null}`, []string{"1:echo:"}},
+		{"blank lines keep numbering", "\n  \n{\"tool\": \"echo\"}\n\n", []string{"3:echo:"}},
+		{"no tool", `{"arguments": {}}`, []string{"1:!job has no tool"}},
+		{"array arguments", `{"tool": "echo", "arguments": [1]}`, []string{"1:!arguments must be a JSON object"}},
+		{"string arguments", `{"tool": "echo", "arguments": "text"}`, []string{"1:!arguments must be a JSON object"}},
+		{"invalid line among valid ones", "{\"tool\": \"a\"}\nnot json\n{\"tool\": \"b\"}", []string{"1:a:", "2:!invalid job", "3:b:"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := readJobs(writeFile(t, "jobs.jsonl", tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, j := range jobs {
+				if j.invalid == "" {
+					got = append(got, fmt.Sprintf("%d:%s:%s", j.line, j.Tool, string(j.Arguments)))
+					continue
+				}
+				// The message of the JSON decoder after the colon is not part of the format
+				reason, _, _ := strings.Cut(j.invalid, ":")
+				got = append(got, fmt.Sprintf("%d:!%s", j.line, reason))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got jobs %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := readJobs(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Fatal("got no error for a missing jobs file")
+	}
+}
+
+func TestCompletedLines(t *testing.T) {
+	results := `{"line": 1, "status": "ok"}
+{"line": 2, "status": "tool_error"}
+{"line": 3, "status": "failed"}
+{"line": 4, "status": "invalid"}
+{"line": 5, "sta`
+
+	for _, tt := range []struct {
+		name    string
+		content *string
+		resume  bool
+		want    []int
+		usage   bool
+	}{
+		{name: "missing file", want: []int{}},
+		{name: "missing file with resume", resume: true, want: []int{}},
+		{name: "empty file", content: ptr(" \n"), want: []int{}},
+		{name: "results without resume", content: &results, usage: true},
+		{name: "results with resume", content: &results, resume: true, want: []int{1, 2}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jobs.results.jsonl")
+			if tt.content != nil {
+				path = writeFile(t, "jobs.results.jsonl", *tt.content)
+			}
+			done, err := completedLines(path, tt.resume)
+			var usage *usageError
+			if tt.usage {
+				if !errors.As(err, &usage) {
+					t.Fatalf("got %v, want a usage error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Sorted(maps.Keys(done)); !slices.Equal(got, tt.want) {
+				t.Fatalf("got completed lines %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenResults(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content *string
+		want    string
+	}{
+		{name: "new file", want: "next\n"},
+		{name: "empty file", content: ptr(""), want: "next\n"},
+		{name: "complete lines", content: ptr("first\n"), want: "first\nnext\n"},
+		{name: "line cut by a crash", content: ptr("first\nsec"), want: "first\nsec\nnext\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jobs.results.jsonl")
+			if tt.content != nil {
+				path = writeFile(t, "jobs.results.jsonl", *tt.content)
+			}
+			file, err := openResults(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = file.Write([]byte("next\n")); err != nil {
+				t.Fatal(err)
+			}
+			if err = file.Close(); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Fatalf("got %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
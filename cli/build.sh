@@ -0,0 +1 @@
+go build -v -o mcpCli .
\ No newline at end of file
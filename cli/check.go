@@ -0,0 +1,481 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mcp/mcpclient"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+// Statuses of a conformance check
+const (
+	checkPass = "PASS"
+	// checkWarn the server does not follow a recommendation (SHOULD) of the specification
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// codeInvalidParams the JSON-RPC error code the specification expects for unknown tools and invalid cursors
+const codeInvalidParams = -32602
+
+// protocolVersions the published versions of the MCP specification
+var protocolVersions = []string{"2024-11-05", "2025-03-26", "2025-06-18"}
+
+// checkResult the outcome of a conformance check
+type checkResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// checker runs the conformance checks against a server
+type checker struct {
+	conn    *mcpclient.Conn
+	results []checkResult
+
+	tools []*mcpclient.Tool
+	// probe is the tool called with malformed arguments
+	probe       string
+	cancelTool  string
+	cancelArgs  json.RawMessage
+	cancelAfter time.Duration
+	cancelWait  time.Duration
+}
+
+// check connects to a server and checks that it follows the MCP specification
+func check(a *app, args []string) error {
+	flags := a.commandFlags("check")
+	probe := flags.String("tool", "", "tool called with malformed arguments, defaults to a tool with required arguments, read-only ones first")
+	cancelTool := flags.String("cancel-tool", "", "tool whose call is cancelled, it must run longer than -cancel-after")
+	cancelArgs := flags.String("cancel-args", "", "arguments of the cancelled call, a JSON object, @file or @- for stdin")
+	cancelAfter := flags.Duration("cancel-after", 500*time.Millisecond, "time after which the call of -cancel-tool is cancelled")
+	cancelWait := flags.Duration("cancel-wait", 3*time.Second, "time to wait for an answer to the cancelled call, which the server should not send")
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usagef("check takes no arguments")
+	}
+	arguments, err := readArguments(*cancelArgs)
+	if err != nil {
+		return usagef("invalid -cancel-args: %v", err)
+	}
+
+	c := &checker{probe: *probe, cancelTool: *cancelTool, cancelArgs: arguments, cancelAfter: *cancelAfter, cancelWait: *cancelWait}
+	if c.conn, err = a.connect(ctx); err != nil {
+		c.add("initialize", checkFail, err.Error())
+	} else {
+		c.run(ctx)
+	}
+	if err = c.report(a.output); err != nil {
+		return err
+	}
+
+	if failed := c.count(checkFail); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(c.results))
+	}
+	return nil
+}
+
+func (c *checker) run(ctx context.Context) {
+	c.initialize()
+	c.ping(ctx)
+	c.lists(ctx)
+	c.schemas()
+	c.unknownTool(ctx)
+	c.malformedArguments(ctx)
+	c.cancellation(ctx)
+}
+
+func (c *checker) add(name, status, detail string) {
+	c.results = append(c.results, checkResult{Name: name, Status: status, Detail: detail})
+}
+
+func (c *checker) count(status string) int {
+	n := 0
+	for _, result := range c.results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *checker) report(output *printer) error {
+	if output.isJSON() {
+		return output.json(c.results)
+	}
+	rows := make([][]string, 0, len(c.results))
+	for _, result := range c.results {
+		rows = append(rows, []string{result.Status, result.Name, result.Detail})
+	}
+	if err := output.table([]string{"STATUS", "CHECK", "DETAIL"}, rows); err != nil {
+		return err
+	}
+	fmt.Fprintf(output.w, "\n%d passed, %d warnings, %d failed, %d skipped\n",
+		c.count(checkPass), c.count(checkWarn), c.count(checkFail), c.count(checkSkip))
+	return nil
+}
+
+// initialize checks the result of the handshake Dial made
+func (c *checker) initialize() {
+	info := c.conn.ServerInfo
+	switch {
+	case info.Name == "":
+		c.add("initialize", checkFail, "serverInfo has no name")
+	case !slices.Contains(protocolVersions, c.conn.ProtocolVersion):
+		c.add("initialize", checkFail, fmt.Sprintf("unknown protocol version %q", c.conn.ProtocolVersion))
+	case info.Version == "":
+		c.add("initialize", checkWarn, "serverInfo has no version")
+	default:
+		c.add("initialize", checkPass, fmt.Sprintf("%s %s, protocol %s", info.Name, info.Version, c.conn.ProtocolVersion))
+	}
+
+	var advertised []string
+	if c.conn.Capabilities.Tools != nil {
+		advertised = append(advertised, "tools")
+	}
+	if c.conn.Capabilities.Prompts != nil {
+		advertised = append(advertised, "prompts")
+	}
+	if c.conn.Capabilities.Resources != nil {
+		advertised = append(advertised, "resources")
+	}
+	if len(advertised) == 0 {
+		c.add("capabilities", checkWarn, "no tools, prompts or resources capability")
+		return
+	}
+	c.add("capabilities", checkPass, strings.Join(advertised, ", "))
+}
+
+func (c *checker) ping(ctx context.Context) {
+	var result map[string]interface{}
+	if err := c.conn.Call(ctx, protocol.Ping, protocol.NewPingRequest(), &result); err != nil {
+		c.add("ping", checkFail, err.Error())
+		return
+	}
+	c.add("ping", checkPass, "")
+}
+
+// lists pages through the lists of the advertised capabilities, then asks for a page with an invalid cursor
+func (c *checker) lists(ctx context.Context) {
+	lists := []struct {
+		method     protocol.Method
+		key        string
+		advertised bool
+	}{
+		{protocol.ToolsList, "tools", c.conn.Capabilities.Tools != nil},
+		{protocol.PromptsList, "prompts", c.conn.Capabilities.Prompts != nil},
+		{protocol.ResourcesList, "resources", c.conn.Capabilities.Resources != nil},
+		{protocol.ResourceListTemplates, "resourceTemplates", c.conn.Capabilities.Resources != nil},
+	}
+	for _, list := range lists {
+		name := string(list.method)
+		if !list.advertised {
+			c.add(name, checkSkip, "capability not advertised")
+			continue
+		}
+		items, pages, err := c.pages(ctx, list.method, list.key)
+		if err != nil {
+			c.add(name, checkFail, err.Error())
+			continue
+		}
+		c.add(name, checkPass, fmt.Sprintf("%d items in %d pages", len(items), pages))
+
+		if list.method == protocol.ToolsList {
+			for _, item := range items {
+				tool := &mcpclient.Tool{}
+				if err = json.Unmarshal(item, tool); err == nil {
+					c.tools = append(c.tools, tool)
+				}
+			}
+			// Servers may list tools in any order, the report and the probe tool should not change between runs
+			slices.SortFunc(c.tools, func(a, b *mcpclient.Tool) int { return strings.Compare(a.Name, b.Name) })
+		}
+		c.invalidCursor(ctx, list.method)
+	}
+}
+
+// pages requests every page of a list. Items are identified by their name, or URI (template) for resources.
+func (c *checker) pages(ctx context.Context, method protocol.Method, key string) ([]json.RawMessage, int, error) {
+	var (
+		items []json.RawMessage
+		names = make(map[string]bool)
+		seen  = make(map[string]bool)
+	)
+	cursor := ""
+	for pages := 1; ; pages++ {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page map[string]json.RawMessage
+		if err := c.conn.Call(ctx, method, params, &page); err != nil {
+			return nil, pages, fmt.Errorf("page %d: %v", pages, err)
+		}
+		var pageItems []json.RawMessage
+		if err := json.Unmarshal(page[key], &pageItems); err != nil {
+			return nil, pages, fmt.Errorf("page %d has no %s array", pages, key)
+		}
+		for _, item := range pageItems {
+			var id struct {
+				Name        string `json:"name"`
+				URI         string `json:"uri"`
+				URITemplate string `json:"uriTemplate"`
+			}
+			json.Unmarshal(item, &id)
+			name := id.Name
+			if id.URI != "" || id.URITemplate != "" {
+				name = id.URI + id.URITemplate
+			}
+			if names[name] {
+				return nil, pages, fmt.Errorf("%s is listed twice", name)
+			}
+			names[name] = true
+			items = append(items, item)
+		}
+
+		cursor = ""
+		if raw, ok := page["nextCursor"]; ok {
+			json.Unmarshal(raw, &cursor)
+		}
+		if cursor == "" {
+			return items, pages, nil
+		}
+		if seen[cursor] {
+			return nil, pages, fmt.Errorf("cursor %q repeats, the pages never end", cursor)
+		}
+		seen[cursor] = true
+	}
+}
+
+func (c *checker) invalidCursor(ctx context.Context, method protocol.Method) {
+	name := string(method) + " invalid cursor"
+	err := c.conn.Call(ctx, method, map[string]string{"cursor": "conformance-invalid-cursor"}, nil)
+	var rpcErr *mcpclient.RPCError
+	switch {
+	case err == nil:
+		c.add(name, checkWarn, "accepted, an invalid cursor should be an error")
+	case !errors.As(err, &rpcErr):
+		c.add(name, checkFail, err.Error())
+	case rpcErr.Code != codeInvalidParams:
+		c.add(name, checkWarn, fmt.Sprintf("error code %d, expected %d", rpcErr.Code, codeInvalidParams))
+	default:
+		c.add(name, checkPass, "")
+	}
+}
+
+// schemas checks that the input schema of every tool is a valid JSON Schema
+func (c *checker) schemas() {
+	for _, tool := range c.tools {
+		name := "inputSchema " + tool.Name
+		if problems := validateInputSchema(tool.InputSchema); len(problems) > 0 {
+			c.add(name, checkFail, strings.Join(problems, "; "))
+			continue
+		}
+		c.add(name, checkPass, "")
+	}
+}
+
+func (c *checker) unknownTool(ctx context.Context) {
+	if c.conn.Capabilities.Tools == nil {
+		c.add("unknown tool", checkSkip, "tools capability not advertised")
+		return
+	}
+	name := fmt.Sprintf("conformance-unknown-tool-%d", time.Now().UnixNano())
+	result, err := c.conn.CallTool(ctx, name, nil, nil)
+	var rpcErr *mcpclient.RPCError
+	switch {
+	case err == nil && result.IsError:
+		c.add("unknown tool", checkWarn, "tool error result, expected a protocol error")
+	case err == nil:
+		c.add("unknown tool", checkFail, "the call succeeded")
+	case !errors.As(err, &rpcErr):
+		c.add("unknown tool", checkFail, err.Error())
+	case rpcErr.Code != codeInvalidParams:
+		c.add("unknown tool", checkWarn, fmt.Sprintf("error code %d, expected %d", rpcErr.Code, codeInvalidParams))
+	default:
+		c.add("unknown tool", checkPass, rpcErr.Message)
+	}
+}
+
+// malformedArguments calls the probe tool with arguments that are not an object, lack a required
+// property or give it the wrong type. Each call must be rejected, by a protocol error or a tool error.
+func (c *checker) malformedArguments(ctx context.Context) {
+	tool := c.probeTool()
+	if tool == nil {
+		reason := "no tool has required arguments"
+		if c.probe != "" {
+			reason = fmt.Sprintf("tool %s not found", c.probe)
+		}
+		c.add("malformed arguments", checkSkip, reason)
+		return
+	}
+
+	sch := parseSchema(tool.InputSchema)
+	c.rejected(ctx, "arguments not an object", tool.Name, json.RawMessage(`"conformance"`))
+	c.rejected(ctx, "missing required argument", tool.Name, json.RawMessage(`{}`))
+
+	// Valid values for the required properties but one of the wrong type
+	for _, target := range sch.Required {
+		prop := sch.Properties[target]
+		if prop == nil || len(prop.types()) == 0 {
+			continue
+		}
+		wrong, ok := wrongValue(prop)
+		if !ok {
+			continue
+		}
+		args := map[string]json.RawMessage{target: wrong}
+		for _, name := range sch.Required {
+			if name != target && sch.Properties[name] != nil {
+				args[name] = sampleValue(sch.Properties[name])
+			}
+		}
+		raw, _ := json.Marshal(args)
+		c.rejected(ctx, "wrong argument type", tool.Name, raw)
+		return
+	}
+	c.add("wrong argument type", checkSkip, fmt.Sprintf("no typed required argument in %s", tool.Name))
+}
+
+// probeTool returns the -tool tool, else the first tool with required arguments, read-only ones first
+// since a server that does not validate arguments runs the tool
+func (c *checker) probeTool() *mcpclient.Tool {
+	var fallback *mcpclient.Tool
+	for _, tool := range c.tools {
+		if c.probe != "" {
+			if tool.Name == c.probe {
+				return tool
+			}
+			continue
+		}
+		if len(parseSchema(tool.InputSchema).Required) == 0 {
+			continue
+		}
+		if tool.Annotations != nil && tool.Annotations.ReadOnlyHint != nil && *tool.Annotations.ReadOnlyHint {
+			return tool
+		}
+		if fallback == nil {
+			fallback = tool
+		}
+	}
+	return fallback
+}
+
+func (c *checker) rejected(ctx context.Context, name, tool string, args json.RawMessage) {
+	name += " (" + tool + ")"
+	result, err := c.conn.CallTool(ctx, tool, args, nil)
+	var rpcErr *mcpclient.RPCError
+	switch {
+	case errors.As(err, &rpcErr):
+		c.add(name, checkPass, rpcErr.Error())
+	case err != nil:
+		c.add(name, checkFail, err.Error())
+	case result.IsError:
+		c.add(name, checkPass, "tool error result")
+	default:
+		c.add(name, checkFail, "the call succeeded")
+	}
+}
+
+// wrongValue returns a value none of the types of the property accept
+func wrongValue(prop *property) (json.RawMessage, bool) {
+	types := prop.types()
+	for _, candidate := range []string{"object", "string", "boolean"} {
+		accepted := slices.Contains(types, candidate) || (candidate == "object" && slices.Contains(types, "array"))
+		if !accepted {
+			return sampleType(candidate), true
+		}
+	}
+	return nil, false
+}
+
+// sampleValue returns a value the property accepts, its first enum value when it has some
+func sampleValue(prop *property) json.RawMessage {
+	if len(prop.Enum) > 0 {
+		return prop.Enum[0]
+	}
+	if types := prop.types(); len(types) > 0 {
+		return sampleType(types[0])
+	}
+	return json.RawMessage(`"conformance"`)
+}
+
+func sampleType(t string) json.RawMessage {
+	switch t {
+	case "integer", "number":
+		return json.RawMessage("1")
+	case "boolean":
+		return json.RawMessage("true")
+	case "null":
+		return json.RawMessage("null")
+	case "array":
+		return json.RawMessage("[]")
+	case "object":
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(`"conformance"`)
+}
+
+// cancellation cancels a notification for a request that does not exist, which must be ignored,
+// and a running call, after which the server must keep answering and should not answer the call
+func (c *checker) cancellation(ctx context.Context) {
+	unknown := protocol.NewCancelledNotification(json.RawMessage(`"conformance-unknown-request"`), "conformance check")
+	if err := c.conn.Notify(ctx, protocol.NotificationCancelled, unknown); err != nil {
+		c.add("cancel unknown request", checkFail, err.Error())
+	} else if err = c.conn.Call(ctx, protocol.Ping, protocol.NewPingRequest(), nil); err != nil {
+		c.add("cancel unknown request", checkFail, fmt.Sprintf("ping afterwards: %v", err))
+	} else {
+		c.add("cancel unknown request", checkPass, "ignored")
+	}
+
+	if c.cancelTool == "" {
+		c.add("cancel running call", checkSkip, "no -cancel-tool given")
+		return
+	}
+	callCtx, cancel := context.WithTimeout(ctx, c.cancelAfter)
+	defer cancel()
+	lateAnswers := c.conn.AnswersAfterCancel()
+	start := time.Now()
+	_, err := c.conn.CallTool(callCtx, c.cancelTool, c.cancelArgs, nil)
+	switch {
+	case ctx.Err() != nil:
+		c.add("cancel running call", checkFail, ctx.Err().Error())
+		return
+	case err == nil:
+		c.add("cancel running call", checkSkip, fmt.Sprintf("%s ended after %s, before it could be cancelled",
+			c.cancelTool, time.Since(start).Round(time.Millisecond)))
+		return
+	case !errors.Is(err, context.DeadlineExceeded):
+		c.add("cancel running call", checkFail, fmt.Sprintf("%s failed before it could be cancelled: %v", c.cancelTool, err))
+		return
+	}
+
+	if err = c.conn.Call(ctx, protocol.Ping, protocol.NewPingRequest(), nil); err != nil {
+		c.add("cancel running call", checkFail, fmt.Sprintf("ping after cancelling: %v", err))
+		return
+	}
+
+	// A server that ignores the cancellation answers once the call ends, the connection drops and counts the answer
+	select {
+	case <-time.After(c.cancelWait):
+	case <-ctx.Done():
+		c.add("cancel running call", checkFail, ctx.Err().Error())
+		return
+	}
+	if c.conn.AnswersAfterCancel() > lateAnswers {
+		c.add("cancel running call", checkWarn, fmt.Sprintf("%s cancelled after %s, the server still sent its result", c.cancelTool, c.cancelAfter))
+		return
+	}
+	c.add("cancel running call", checkPass, fmt.Sprintf("%s cancelled after %s, no result within %s and the server still answers",
+		c.cancelTool, c.cancelAfter, c.cancelWait))
+}
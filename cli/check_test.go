@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mcp/logging"
+	"mcp/mcpclient"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+// apiDelay lets the cancellation check cancel text_to_audio while the server waits for the fake API
+const apiDelay = 2 * time.Second
+
+// apiStats the counters of the fake MiniMax API
+type apiStats struct {
+	Requests int64 `json:"requests"`
+	Aborted  int64 `json:"aborted"`
+}
+
+// TestConformance runs the checks of the check command against the MiniMax server over every transport,
+// with the MiniMax API answered by the fake API
+func TestConformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and starts the MiniMax server and the fake MiniMax API")
+	}
+	bin := t.TempDir()
+	fakeAPI := buildBinary(t, bin, "fakeMiniMaxAPI", "../minimax/fakeapi")
+	server := buildBinary(t, bin, "miniMaxMCPServer", "../minimax/server")
+
+	apiAddr := freeAddr(t)
+	startProcess(t, bin, fakeAPI, "-addr", apiAddr, "-delay", apiDelay.String(), "-log-level", "warn")
+	waitFor(t, func() error {
+		_, err := readStats("http://" + apiAddr)
+		return err
+	})
+
+	for _, transport := range []string{mcpclient.Stdio, mcpclient.SSE, mcpclient.Streamable} {
+		t.Run(transport, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := mcpclient.Config{Transport: transport}
+			addr := ""
+			switch transport {
+			case mcpclient.SSE:
+				addr = freeAddr(t)
+				cfg.URL = "http://" + addr + "/sse"
+			case mcpclient.Streamable:
+				addr = freeAddr(t)
+				cfg.URL = "http://" + addr + "/mcp"
+			default:
+				cfg.Command = server
+			}
+			writeConfig(t, dir, transport, apiAddr, addr)
+			if transport == mcpclient.Stdio {
+				// The server reads config.ini from its working directory, which it inherits
+				t.Chdir(dir)
+			} else {
+				startProcess(t, dir, server)
+			}
+
+			conn := dialServer(t, cfg)
+			before, err := readStats("http://" + apiAddr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := &checker{
+				conn:        conn,
+				cancelTool:  "text_to_audio",
+				cancelArgs:  json.RawMessage(`{"text": "conformance"}`),
+				cancelAfter: 500 * time.Millisecond,
+				cancelWait:  apiDelay + time.Second,
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			c.run(ctx)
+
+			for _, result := range c.results {
+				t.Logf("%s %s %s", result.Status, result.Name, result.Detail)
+				if result.Status == checkFail {
+					t.Errorf("check %s failed: %s", result.Name, result.Detail)
+				}
+				if result.Name == "cancel running call" && result.Status != checkPass {
+					t.Errorf("check %s: %s %s", result.Name, result.Status, result.Detail)
+				}
+			}
+
+			after, err := readStats("http://" + apiAddr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after.Aborted <= before.Aborted {
+				t.Errorf("the fake API saw no aborted request, the server did not cancel the text_to_audio call")
+			}
+		})
+	}
+}
+
+// buildBinary builds a package of the module into dir
+func buildBinary(t *testing.T, dir, name, pkg string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if out, err := exec.Command("go", "build", "-o", path, pkg).CombinedOutput(); err != nil {
+		t.Fatalf("failed to build %s: %v\n%s", pkg, err, out)
+	}
+	return path
+}
+
+// freeAddr returns a local address no one listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// startProcess runs a command in dir until the test ends
+func startProcess(t *testing.T, dir, name string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+}
+
+// writeConfig writes the server configuration, logs go to server.log in dir
+func writeConfig(t *testing.T, dir, mode, apiAddr, addr string) {
+	t.Helper()
+	config := fmt.Sprintf(`[Minimax]
+APIKey = fake-key
+APIHost = http://%s
+ResourceMode = local
+Mode = %s
+Addr = %s
+[Log]
+Level = warn
+Output = %s
+[Shutdown]
+GracePeriod = 1s
+`, apiAddr, mode, addr, filepath.Join(dir, "server.log"))
+	if err := os.WriteFile(filepath.Join(dir, "config.ini"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// dialServer connects to the server once it listens
+func dialServer(t *testing.T, cfg mcpclient.Config) *mcpclient.Conn {
+	t.Helper()
+	logger := logging.SDKLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var conn *mcpclient.Conn
+	waitFor(t, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var err error
+		conn, err = mcpclient.Dial(ctx, cfg, logger, protocol.Implementation{Name: "mcpCli", Version: "test"})
+		return err
+	})
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// waitFor calls try until it succeeds, for at most ten seconds
+func waitFor(t *testing.T, try func() error) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		err := try()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("gave up waiting: %v", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func readStats(apiURL string) (*apiStats, error) {
+	resp, err := http.Get(apiURL + "/_fake/stats")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	stats := &apiStats{}
+	if err = json.NewDecoder(resp.Body).Decode(stats); err != nil {
+		return nil, fmt.Errorf("invalid stats: %v", err)
+	}
+	return stats, nil
+}
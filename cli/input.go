@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// maxHistory bounds the lines kept in the history file
+const maxHistory = 1000
+
+// errInterrupted reports Ctrl-C or Ctrl-D while reading a line
+var errInterrupted = errors.New("interrupted")
+
+// lineReader reads the lines typed in the shell
+type lineReader interface {
+	// ReadLine reads a line after prompt, record adds it to the history
+	ReadLine(prompt string, record bool) (string, error)
+	// Interruptible returns a context cancelled by Ctrl-C until stop is called.
+	// Lines printed meanwhile must end with EOL.
+	Interruptible(ctx context.Context) (_ context.Context, stop func())
+	EOL() string
+	Close() error
+}
+
+// newLineReader returns a line editor with completion and history when stdin is a terminal,
+// a plain line reader otherwise so that scripts can be piped in
+func newLineReader(complete func(line string) []string, historyPath string) (lineReader, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return &plainReader{scanner: bufio.NewScanner(os.Stdin)}, nil
+	}
+
+	history, err := openHistory(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	t := &terminalReader{fd: fd, history: history, complete: complete, input: newStdinPump()}
+	t.reset()
+	return t, nil
+}
+
+// terminalReader edits lines in raw mode. Calls also run in raw mode, watching the input for Ctrl-C:
+// the SIGINT of a cooked terminal would reach the whole process group, including a stdio server.
+type terminalReader struct {
+	fd       int
+	terminal *term.Terminal
+	history  *history
+	complete func(line string) []string
+	input    *stdinPump
+}
+
+// reset starts a new line editor, the previous one keeps the Ctrl-C that ended its last line as pending input
+func (t *terminalReader) reset() {
+	t.terminal = term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{t.input, os.Stdout}, "")
+	t.terminal.History = t.history
+	t.terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return t.completeLine(line, pos)
+	}
+}
+
+func (t *terminalReader) ReadLine(prompt string, record bool) (string, error) {
+	state, err := term.MakeRaw(t.fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(t.fd, state)
+	if width, height, err := term.GetSize(t.fd); err == nil && width > 0 {
+		t.terminal.SetSize(width, height)
+	}
+
+	t.history.recording = record
+	t.terminal.SetPrompt(prompt)
+	line, err := t.terminal.ReadLine()
+	if errors.Is(err, io.EOF) {
+		t.reset()
+		return "", errInterrupted
+	}
+	return line, err
+}
+
+func (t *terminalReader) Interruptible(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	state, err := term.MakeRaw(t.fd)
+	if err != nil {
+		// Without raw mode Ctrl-C is a signal
+		signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		return signalCtx, func() { stop(); cancel() }
+	}
+
+	unwatch := t.input.watch(keyCtrlC, cancel)
+	return ctx, func() {
+		unwatch()
+		term.Restore(t.fd, state)
+		cancel()
+	}
+}
+
+func (t *terminalReader) EOL() string {
+	return "\r\n"
+}
+
+func (t *terminalReader) Close() error {
+	return t.history.Close()
+}
+
+// completeLine completes the word before the cursor: a single candidate replaces it,
+// several extend it to their common prefix or are listed when it cannot be extended
+func (t *terminalReader) completeLine(line string, pos int) (string, int, bool) {
+	before, after := line[:pos], line[pos:]
+	start := strings.LastIndexAny(before, " \t") + 1
+	word := before[start:]
+
+	var matches []string
+	for _, candidate := range t.complete(before[:start]) {
+		if strings.HasPrefix(candidate, word) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", 0, false
+	case 1:
+		completed := matches[0]
+		// Argument keys end with "=", the value follows without a space
+		if !strings.HasSuffix(completed, "=") {
+			completed += " "
+		}
+		return before[:start] + completed + after, start + len(completed), true
+	}
+
+	prefix := commonPrefix(matches)
+	if len(prefix) > len(word) {
+		return before[:start] + prefix + after, start + len(prefix), true
+	}
+	fmt.Fprintln(t.terminal, strings.Join(matches, "  "))
+	return line, pos, true
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// plainReader reads lines without editing, e.g. from a pipe
+type plainReader struct {
+	scanner *bufio.Scanner
+}
+
+func (p *plainReader) ReadLine(prompt string, _ bool) (string, error) {
+	fmt.Print(prompt)
+	if !p.scanner.Scan() {
+		if err := p.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", errInterrupted
+	}
+	return p.scanner.Text(), nil
+}
+
+func (p *plainReader) Interruptible(ctx context.Context) (context.Context, func()) {
+	return signal.NotifyContext(ctx, os.Interrupt)
+}
+
+func (p *plainReader) EOL() string {
+	return "\n"
+}
+
+func (p *plainReader) Close() error {
+	return nil
+}
+
+// keyCtrlC is the byte a raw terminal sends for Ctrl-C
+const keyCtrlC = 3
+
+// stdinPump reads stdin in the background so that the input can be watched for Ctrl-C while
+// a call runs and handed to the line editor afterwards, including what was typed ahead
+type stdinPump struct {
+	chunks chan []byte
+
+	mu      sync.Mutex
+	pending []byte
+}
+
+func newStdinPump() *stdinPump {
+	p := &stdinPump{chunks: make(chan []byte)}
+	go func() {
+		defer close(p.chunks)
+		for {
+			buf := make([]byte, 256)
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				p.chunks <- buf[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *stdinPump) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	if len(p.pending) > 0 {
+		n := copy(b, p.pending)
+		p.pending = p.pending[n:]
+		p.mu.Unlock()
+		return n, nil
+	}
+	p.mu.Unlock()
+
+	chunk, ok := <-p.chunks
+	if !ok {
+		return 0, io.EOF
+	}
+	n := copy(b, chunk)
+	if n < len(chunk) {
+		p.mu.Lock()
+		p.pending = append(p.pending, chunk[n:]...)
+		p.mu.Unlock()
+	}
+	return n, nil
+}
+
+// watch calls onKey when key is typed, until the returned function is called.
+// Everything else typed meanwhile is kept for the next Read.
+func (p *stdinPump) watch(key byte, onKey func()) func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case chunk, ok := <-p.chunks:
+				if !ok {
+					return
+				}
+				if i := bytes.IndexByte(chunk, key); i >= 0 {
+					chunk = append(chunk[:i:i], chunk[i+1:]...)
+					onKey()
+				}
+				p.mu.Lock()
+				p.pending = append(p.pending, chunk...)
+				p.mu.Unlock()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// history the lines typed in the shell, kept in a file across sessions.
+// Answers to field prompts are not recorded.
+type history struct {
+	entries   []string
+	file      *os.File
+	recording bool
+}
+
+// openHistory loads the history file, an empty path keeps the history in memory
+func openHistory(path string) (*history, error) {
+	h := &history{}
+	if path == "" {
+		return h, nil
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" {
+				h.entries = append(h.entries, line)
+			}
+		}
+		if len(h.entries) > maxHistory {
+			h.entries = h.entries[len(h.entries)-maxHistory:]
+			// Rewrite the file so that it does not grow forever
+			if err = os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600); err != nil {
+				return nil, fmt.Errorf("failed to truncate history file: %v", err)
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read history file: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %v", err)
+	}
+	h.file = file
+	return h, nil
+}
+
+func (h *history) Add(entry string) {
+	if !h.recording || strings.TrimSpace(entry) == "" {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == entry {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if h.file != nil {
+		fmt.Fprintln(h.file, entry)
+	}
+}
+
+func (h *history) Len() int {
+	return len(h.entries)
+}
+
+func (h *history) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+func (h *history) Close() error {
+	if h.file == nil {
+		return nil
+	}
+	return h.file.Close()
+}
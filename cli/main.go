@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mcp/logging"
+	"mcp/mcpclient"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+// Exit codes
+const (
+	exitOK = 0
+	// exitToolError the tool ran and reported a failure in its result
+	exitToolError = 1
+	// exitUsage invalid command line
+	exitUsage = 2
+	// exitFailure the server could not be reached or answered with a protocol error
+	exitFailure = 3
+)
+
+const usage = `Usage: mcpCli [flags] <command> [args] [flags]
+
+Commands:
+  tools list                       list the tools of the server
+  tools call <name> [-args JSON]   call a tool, -args takes a JSON object, @file or @- for stdin,
+                                   -save-dir writes image, audio and resource items to files
+  resources list                   list the resources of the server
+  resources templates              list the resource templates of the server
+  resources read <uri>             read a resource
+  prompts list                     list the prompts of the server
+  prompts get <name> [-arg k=v]    render a prompt, -arg is repeatable
+  batch <jobs.jsonl> [-resume]     call the tools of a JSONL file of {"tool", "arguments"} lines,
+                                   see -concurrency, -rate and -results
+  check [-tool name]               check that the server follows the MCP specification, see -cancel-tool
+  shell [-history file]            explore the server interactively, with completion and history
+
+Examples:
+  mcpCli -url http://127.0.0.1:8080/mcp tools list
+  mcpCli -transport sse -url "https://mcp.amap.com/sse?key=..." tools call maps_geo -args '{"address": "厦门软件园三期"}'
+  mcpCli -transport stdio -command ./miniMaxMCPServer -output json tools call list_voices
+  mcpCli -url http://127.0.0.1:8080/mcp batch jobs.jsonl -concurrency 2 -rate 0.5 -results results.jsonl
+
+Exit codes: 0 success, 1 the tool returned an error result, 2 invalid usage, 3 connection or protocol failure.
+
+Flags:
+`
+
+var (
+	// errToolFailed reports a tool result with IsError set, the result itself has been printed
+	errToolFailed = errors.New("tool returned an error")
+	// errHelp reports that the usage of a command was asked for and printed
+	errHelp = errors.New("help requested")
+)
+
+// usageError an invalid command line
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// command runs a subcommand with its arguments
+type command func(a *app, args []string) error
+
+var commands = map[string]map[string]command{
+	"tools": {
+		"list": toolsList,
+		"call": toolsCall,
+	},
+	"resources": {
+		"list":      resourcesList,
+		"templates": resourcesTemplates,
+		"read":      resourcesRead,
+	},
+	"prompts": {
+		"list": promptsList,
+		"get":  promptsGet,
+	},
+}
+
+// topCommands the commands without a subcommand
+var topCommands = map[string]command{
+	"batch": batch,
+	"check": check,
+	"shell": shell,
+}
+
+// app the state shared by the subcommands.
+// The global flags are accepted before the command as well as among its own flags, so the connection
+// settings are only known once the command parsed its arguments.
+type app struct {
+	flags *flag.FlagSet
+	opts  options
+
+	ctx    context.Context
+	stop   []func()
+	server mcpclient.Config
+	logger pkg.Logger
+	output *printer
+	conn   *mcpclient.Conn
+}
+
+// options the global flags
+type options struct {
+	transport string
+	url       string
+	command   string
+	env       []string
+	headers   []string
+	token     string
+	output    string
+	timeout   time.Duration
+	verbose   bool
+}
+
+func newApp(ctx context.Context) *app {
+	a := &app{ctx: ctx}
+	a.flags = flag.NewFlagSet("mcpCli", flag.ContinueOnError)
+	a.flags.Usage = func() {
+		fmt.Fprint(a.flags.Output(), usage)
+		a.flags.PrintDefaults()
+	}
+	a.flags.StringVar(&a.opts.transport, "transport", mcpclient.Streamable, "transport: stdio, sse or streamable")
+	a.flags.StringVar(&a.opts.url, "url", "", "URL of the sse (e.g. http://host/sse) or streamable (e.g. http://host/mcp) endpoint")
+	a.flags.StringVar(&a.opts.command, "command", "", "command line starting a stdio server, split on spaces")
+	a.flags.Func("env", "KEY=VALUE added to the environment of the stdio server, repeatable", func(value string) error {
+		a.opts.env = append(a.opts.env, value)
+		return nil
+	})
+	a.flags.Func("header", `HTTP header sent to the server, "Key: Value", repeatable`, func(value string) error {
+		a.opts.headers = append(a.opts.headers, value)
+		return nil
+	})
+	a.flags.StringVar(&a.opts.token, "token", os.Getenv("MCP_TOKEN"), "bearer token sent as the Authorization header, defaults to $MCP_TOKEN")
+	a.flags.StringVar(&a.opts.output, "output", formatTable, "output format: table or json")
+	a.flags.DurationVar(&a.opts.timeout, "timeout", 0, "timeout of the whole command, 0 waits until interrupted")
+	a.flags.BoolVar(&a.opts.verbose, "v", false, "log the exchange with the server to stderr")
+	return a
+}
+
+// commandFlags creates the flag set of a subcommand, it also accepts the global flags
+func (a *app) commandFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	a.flags.VisitAll(func(f *flag.Flag) {
+		flags.Var(f.Value, f.Name, f.Usage)
+	})
+	return flags
+}
+
+// parse parses the arguments of a subcommand, whose flags may come before or after its positional arguments,
+// and applies the global flags. The returned context ends with the command timeout.
+func (a *app) parse(flags *flag.FlagSet, args []string) (context.Context, []string, error) {
+	flags.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintf(os.Stderr, "Usage of %s:\n", flags.Name())
+				flags.SetOutput(os.Stderr)
+				flags.PrintDefaults()
+				return nil, nil, errHelp
+			}
+			return nil, nil, &usageError{msg: err.Error()}
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	// configure replaces a.ctx with the timeout context, read it afterwards
+	if err := a.configure(); err != nil {
+		return nil, nil, err
+	}
+	return a.ctx, positional, nil
+}
+
+// configure applies the global flags
+func (a *app) configure() error {
+	level := "warn"
+	if a.opts.verbose {
+		level = "debug"
+	}
+	// Logs go to stderr, stdout carries the command output
+	logger, _, err := logging.New(logging.Config{Level: level, Stdio: true, Secrets: []string{a.opts.token}})
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	a.logger = logging.SDKLogger(logger)
+
+	header, err := mcpclient.ParseHeaders(a.opts.headers)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	if a.opts.token != "" {
+		header.Set("Authorization", "Bearer "+a.opts.token)
+	}
+	if a.output, err = newPrinter(a.opts.output, os.Stdout); err != nil {
+		return usagef("%v", err)
+	}
+	a.server = mcpclient.Config{
+		Transport: a.opts.transport,
+		URL:       a.opts.url,
+		Env:       a.opts.env,
+		Header:    header,
+	}
+	if fields := strings.Fields(a.opts.command); len(fields) > 0 {
+		a.server.Command, a.server.Args = fields[0], fields[1:]
+	}
+	if err = a.server.Validate(); err != nil {
+		return usagef("%v", err)
+	}
+
+	if a.opts.timeout > 0 {
+		ctx, cancel := context.WithTimeout(a.ctx, a.opts.timeout)
+		a.ctx = ctx
+		a.stop = append(a.stop, cancel)
+	}
+	return nil
+}
+
+// connect dials the server on first use, usage errors are reported before any connection is made
+func (a *app) connect(ctx context.Context) (*mcpclient.Conn, error) {
+	if a.conn != nil {
+		return a.conn, nil
+	}
+	conn, err := mcpclient.Dial(ctx, a.server, a.logger, protocol.Implementation{Name: "mcpCli", Version: "1.0.0"})
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("Connected", "server", conn.ServerInfo.Name, "version", conn.ServerInfo.Version,
+		"protocol_version", conn.ProtocolVersion)
+	a.conn = conn
+	return conn, nil
+}
+
+// close ends the connection, without waiting longer than timeout for the server
+func (a *app) close(timeout time.Duration) {
+	for _, stop := range a.stop {
+		stop()
+	}
+	if a.conn == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		if err := a.conn.Close(); err != nil {
+			slog.Debug("Failed to close connection", "error", err)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	// Interrupting the command cancels the pending request on the server
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a := newApp(ctx)
+	if err := a.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	rest := a.flags.Args()
+	if len(rest) > 0 {
+		if cmd, ok := topCommands[rest[0]]; ok {
+			err := cmd(a, rest[1:])
+			a.close(5 * time.Second)
+			return report(err)
+		}
+	}
+	if len(rest) < 2 {
+		a.flags.Usage()
+		return exitUsage
+	}
+	group, ok := commands[rest[0]]
+	if !ok {
+		return report(usagef("unknown command %q, expected tools, resources, prompts, batch, check or shell", rest[0]))
+	}
+	cmd, ok := group[rest[1]]
+	if !ok {
+		return report(usagef("unknown command %q %q", rest[0], rest[1]))
+	}
+
+	err := cmd(a, rest[2:])
+	a.close(5 * time.Second)
+	return report(err)
+}
+
+// report prints an error and returns the exit code it maps to
+func report(err error) int {
+	var usageErr *usageError
+	switch {
+	case err == nil, errors.Is(err, errHelp):
+		return exitOK
+	case errors.Is(err, errToolFailed):
+		return exitToolError
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(os.Stderr, "mcpCli: %v, run mcpCli -h for usage\n", err)
+		return exitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "mcpCli: %v\n", err)
+		return exitFailure
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mcp/mcpclient"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// maxDescription bounds the descriptions shown in tables
+const maxDescription = 80
+
+// printer writes command results in the chosen format
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+func newPrinter(format string, w io.Writer) (*printer, error) {
+	if format != formatTable && format != formatJSON {
+		return nil, fmt.Errorf("unknown output format %q, expected table or json", format)
+	}
+	return &printer{format: format, w: w}, nil
+}
+
+func (p *printer) isJSON() bool {
+	return p.format == formatJSON
+}
+
+// json prints v as indented JSON
+func (p *printer) json(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// table prints rows in aligned columns under a header
+func (p *printer) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// content prints the content items of a tool result or prompt message, binary data is summarized
+func (p *printer) content(items []mcpclient.Content) {
+	for _, item := range items {
+		switch item.Type {
+		case "text":
+			fmt.Fprintln(p.w, item.Text)
+		case "image", "audio":
+			fmt.Fprintf(p.w, "[%s %s, %s]\n", item.Type, item.MimeType, dataSize(item.Data))
+		case "resource_link":
+			fmt.Fprintf(p.w, "[resource link %s %s]\n", item.URI, item.Name)
+		case "resource":
+			if item.Resource == nil {
+				fmt.Fprintln(p.w, "[empty resource]")
+				continue
+			}
+			p.resource(*item.Resource)
+		default:
+			fmt.Fprintf(p.w, "[%s content]\n", item.Type)
+		}
+	}
+}
+
+// resource prints the contents of a resource, blobs are summarized
+func (p *printer) resource(contents mcpclient.ResourceContents) {
+	if contents.Blob != "" {
+		fmt.Fprintf(p.w, "[resource %s %s, %s]\n", contents.URI, contents.MimeType, dataSize(contents.Blob))
+		return
+	}
+	fmt.Fprintln(p.w, contents.Text)
+}
+
+// dataSize describes the decoded size of base64 data
+func dataSize(data string) string {
+	size := base64.StdEncoding.DecodedLen(len(data)) - strings.Count(data[max(0, len(data)-2):], "=")
+	return fmt.Sprintf("%d bytes", size)
+}
+
+// summary shortens a description to its first line
+func summary(description string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	if runes := []rune(line); len(runes) > maxDescription {
+		return string(runes[:maxDescription-3]) + "..."
+	}
+	return line
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"mcp/mcpclient"
+	"strings"
+)
+
+func promptsList(a *app, args []string) error {
+	flags := a.commandFlags("prompts list")
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usagef("prompts list takes no arguments")
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	prompts, err := conn.ListPrompts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list prompts: %v", err)
+	}
+
+	if a.output.isJSON() {
+		return a.output.json(prompts)
+	}
+	rows := make([][]string, 0, len(prompts))
+	for _, prompt := range prompts {
+		var arguments []string
+		for _, arg := range prompt.Arguments {
+			if arg.Required {
+				arguments = append(arguments, arg.Name)
+			} else {
+				arguments = append(arguments, "["+arg.Name+"]")
+			}
+		}
+		rows = append(rows, []string{prompt.Name, strings.Join(arguments, " "), summary(prompt.Description)})
+	}
+	return a.output.table([]string{"NAME", "ARGUMENTS", "DESCRIPTION"}, rows)
+}
+
+func promptsGet(a *app, args []string) error {
+	arguments := make(map[string]string)
+	flags := a.commandFlags("prompts get")
+	flags.Func("arg", "prompt argument as key=value, repeatable", func(value string) error {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected key=value")
+		}
+		arguments[key] = val
+		return nil
+	})
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usagef("prompts get takes exactly one prompt name")
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := conn.GetPrompt(ctx, positional[0], arguments)
+	if err != nil {
+		return fmt.Errorf("failed to get prompt %s: %v", positional[0], err)
+	}
+
+	if a.output.isJSON() {
+		return a.output.json(result)
+	}
+	if result.Description != "" {
+		fmt.Fprintf(a.output.w, "# %s\n", result.Description)
+	}
+	for _, message := range result.Messages {
+		fmt.Fprintf(a.output.w, "[%s]\n", message.Role)
+		a.output.content([]mcpclient.Content{message.Content})
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+)
+
+func resourcesList(a *app, args []string) error {
+	flags := a.commandFlags("resources list")
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usagef("resources list takes no arguments")
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	resources, err := conn.ListResources(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list resources: %v", err)
+	}
+
+	if a.output.isJSON() {
+		return a.output.json(resources)
+	}
+	rows := make([][]string, 0, len(resources))
+	for _, resource := range resources {
+		rows = append(rows, []string{resource.URI, resource.Name, resource.MimeType, summary(resource.Description)})
+	}
+	return a.output.table([]string{"URI", "NAME", "MIME TYPE", "DESCRIPTION"}, rows)
+}
+
+func resourcesTemplates(a *app, args []string) error {
+	flags := a.commandFlags("resources templates")
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usagef("resources templates takes no arguments")
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	templates, err := conn.ListResourceTemplates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list resource templates: %v", err)
+	}
+
+	if a.output.isJSON() {
+		return a.output.json(templates)
+	}
+	rows := make([][]string, 0, len(templates))
+	for _, template := range templates {
+		rows = append(rows, []string{template.URITemplate, template.Name, template.MimeType, summary(template.Description)})
+	}
+	return a.output.table([]string{"URI TEMPLATE", "NAME", "MIME TYPE", "DESCRIPTION"}, rows)
+}
+
+func resourcesRead(a *app, args []string) error {
+	flags := a.commandFlags("resources read")
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usagef("resources read takes exactly one URI")
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	contents, err := conn.ReadResource(ctx, positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read resource %s: %v", positional[0], err)
+	}
+
+	if a.output.isJSON() {
+		return a.output.json(contents)
+	}
+	for _, c := range contents {
+		a.output.resource(c)
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// schema the parts of a tool input schema the shell uses to complete and check arguments
+type schema struct {
+	Properties map[string]*property `json:"properties"`
+	Required   []string             `json:"required"`
+}
+
+// property a property of an input schema, Type is a string or a list of strings
+type property struct {
+	Type        interface{}       `json:"type"`
+	Description string            `json:"description"`
+	Enum        []json.RawMessage `json:"enum"`
+}
+
+func parseSchema(raw json.RawMessage) *schema {
+	s := &schema{}
+	if err := json.Unmarshal(raw, s); err != nil {
+		return &schema{}
+	}
+	return s
+}
+
+// names returns the property names, required ones first in schema order
+func (s *schema) names() []string {
+	var names []string
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	var optional []string
+	for name := range s.Properties {
+		if !slices.Contains(names, name) {
+			optional = append(optional, name)
+		}
+	}
+	slices.Sort(optional)
+	return append(names, optional...)
+}
+
+// types returns the JSON types the property accepts, empty when any
+func (p *property) types() []string {
+	switch t := p.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// describe summarizes the property for a prompt, e.g. "integer, the number of images"
+func (p *property) describe() string {
+	parts := []string{strings.Join(p.types(), "|")}
+	if len(p.Enum) > 0 {
+		values := make([]string, 0, len(p.Enum))
+		for _, value := range p.Enum {
+			values = append(values, string(value))
+		}
+		parts = append(parts, "one of "+strings.Join(values, ", "))
+	}
+	if p.Description != "" {
+		parts = append(parts, summary(p.Description))
+	}
+	if parts[0] == "" {
+		parts = parts[1:]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parse converts a value typed in the shell to the JSON type of the property.
+// Strings are taken literally, other types are parsed, arrays and objects as JSON.
+func (p *property) parse(input string) (json.RawMessage, error) {
+	types := p.types()
+	if len(types) == 0 {
+		// Untyped: JSON when it parses, a string otherwise
+		if json.Valid([]byte(input)) {
+			return p.checkEnum(json.RawMessage(input))
+		}
+		types = []string{"string"}
+	}
+
+	var errs []string
+	for _, t := range types {
+		value, err := parseTyped(t, input)
+		if err == nil {
+			return p.checkEnum(value)
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("%s", strings.Join(errs, ", "))
+}
+
+func (p *property) checkEnum(value json.RawMessage) (json.RawMessage, error) {
+	if len(p.Enum) == 0 {
+		return value, nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(value, &decoded); err != nil {
+		return nil, err
+	}
+	for _, allowed := range p.Enum {
+		var candidate interface{}
+		if json.Unmarshal(allowed, &candidate) == nil && fmt.Sprint(candidate) == fmt.Sprint(decoded) {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not one of the allowed values", value)
+}
+
+func parseTyped(t, input string) (json.RawMessage, error) {
+	switch t {
+	case "string":
+		return json.Marshal(input)
+	case "integer":
+		if _, err := strconv.ParseInt(input, 10, 64); err != nil {
+			return nil, fmt.Errorf("%q is not an integer", input)
+		}
+		return json.RawMessage(input), nil
+	case "number":
+		if _, err := strconv.ParseFloat(input, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a number", input)
+		}
+		return json.RawMessage(input), nil
+	case "boolean":
+		b, err := strconv.ParseBool(input)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", input)
+		}
+		return json.Marshal(b)
+	case "null":
+		if input != "null" {
+			return nil, fmt.Errorf("%q is not null", input)
+		}
+		return json.RawMessage("null"), nil
+	case "array", "object":
+		var value interface{}
+		if err := json.Unmarshal([]byte(input), &value); err != nil {
+			return nil, fmt.Errorf("%q is not a JSON %s", input, t)
+		}
+		if _, isArray := value.([]interface{}); isArray != (t == "array") {
+			return nil, fmt.Errorf("%q is not a JSON %s", input, t)
+		}
+		if _, isObject := value.(map[string]interface{}); isObject != (t == "object") {
+			return nil, fmt.Errorf("%q is not a JSON %s", input, t)
+		}
+		return json.RawMessage(input), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// jsonTypes the type names of JSON Schema
+var jsonTypes = []string{"string", "integer", "number", "boolean", "null", "array", "object"}
+
+// validateInputSchema checks that a tool input schema is a valid JSON Schema describing an object.
+// Keywords are checked against their definition in drafts 7 to 2020-12, unknown keywords are allowed.
+func validateInputSchema(raw json.RawMessage) []string {
+	var root interface{}
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return []string{fmt.Sprintf("not JSON: %v", err)}
+	}
+	object, ok := root.(map[string]interface{})
+	if !ok {
+		return []string{"the input schema is not a JSON object"}
+	}
+	var problems []string
+	if object["type"] != "object" {
+		problems = append(problems, `the input schema type is not "object"`)
+	}
+	return append(problems, validateSchema("#", root)...)
+}
+
+// validateSchema checks the keywords of the schema at path and of its subschemas
+func validateSchema(path string, value interface{}) []string {
+	if _, ok := value.(bool); ok {
+		return nil
+	}
+	schema, ok := value.(map[string]interface{})
+	if !ok {
+		return []string{path + " is not a schema"}
+	}
+
+	var problems []string
+	fail := func(keyword, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s/%s %s", path, keyword, fmt.Sprintf(format, args...)))
+	}
+	keywords := make([]string, 0, len(schema))
+	for keyword := range schema {
+		keywords = append(keywords, keyword)
+	}
+	slices.Sort(keywords)
+
+	for _, keyword := range keywords {
+		v := schema[keyword]
+		sub := path + "/" + keyword
+		switch keyword {
+		case "type":
+			types, isList := v.([]interface{})
+			if !isList {
+				types = []interface{}{v}
+			} else if len(types) == 0 {
+				fail(keyword, "is empty")
+			}
+			var seen []string
+			for _, t := range types {
+				name, _ := t.(string)
+				if !slices.Contains(jsonTypes, name) {
+					fail(keyword, "has unknown type %v", t)
+				} else if slices.Contains(seen, name) {
+					fail(keyword, "repeats %s", name)
+				}
+				seen = append(seen, name)
+			}
+		case "properties", "patternProperties", "$defs", "definitions", "dependentSchemas":
+			object, ok := v.(map[string]interface{})
+			if !ok {
+				fail(keyword, "is not an object")
+				continue
+			}
+			names := make([]string, 0, len(object))
+			for name := range object {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				problems = append(problems, validateSchema(sub+"/"+name, object[name])...)
+			}
+		case "required":
+			items, ok := v.([]interface{})
+			if !ok {
+				fail(keyword, "is not an array")
+				continue
+			}
+			var seen []string
+			for _, item := range items {
+				name, ok := item.(string)
+				if !ok {
+					fail(keyword, "has a non-string item %v", item)
+				} else if slices.Contains(seen, name) {
+					fail(keyword, "repeats %s", name)
+				}
+				seen = append(seen, name)
+			}
+		case "items":
+			// An array of schemas is the draft 7 tuple form
+			if items, ok := v.([]interface{}); ok {
+				for i, item := range items {
+					problems = append(problems, validateSchema(fmt.Sprintf("%s/%d", sub, i), item)...)
+				}
+				continue
+			}
+			problems = append(problems, validateSchema(sub, v)...)
+		case "prefixItems", "allOf", "anyOf", "oneOf":
+			items, ok := v.([]interface{})
+			if !ok || len(items) == 0 {
+				fail(keyword, "is not a non-empty array")
+				continue
+			}
+			for i, item := range items {
+				problems = append(problems, validateSchema(fmt.Sprintf("%s/%d", sub, i), item)...)
+			}
+		case "additionalProperties", "additionalItems", "unevaluatedProperties", "unevaluatedItems",
+			"not", "if", "then", "else", "contains", "propertyNames":
+			problems = append(problems, validateSchema(sub, v)...)
+		case "enum":
+			if _, ok := v.([]interface{}); !ok {
+				fail(keyword, "is not an array")
+			}
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			if _, ok := v.(float64); !ok {
+				fail(keyword, "is not a number")
+			}
+		case "multipleOf":
+			if n, ok := v.(float64); !ok || n <= 0 {
+				fail(keyword, "is not a number greater than 0")
+			}
+		case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties", "minContains", "maxContains":
+			if n, ok := v.(float64); !ok || n < 0 || n != float64(int64(n)) {
+				fail(keyword, "is not a non-negative integer")
+			}
+		case "uniqueItems", "readOnly", "writeOnly", "deprecated":
+			if _, ok := v.(bool); !ok {
+				fail(keyword, "is not a boolean")
+			}
+		case "pattern", "format", "title", "description", "$ref", "$schema", "$id", "$anchor", "$comment":
+			if _, ok := v.(string); !ok {
+				fail(keyword, "is not a string")
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,605 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mcp/mcpclient"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+const shellHelp = `Commands:
+  tools                          list the tools
+  schema <tool>                  show the input schema of a tool
+  call <tool> [key=value ...]    call a tool, missing required arguments are asked for
+  call <tool> {JSON}             call a tool with a JSON object of arguments
+  resources                      list the resources
+  read <uri>                     read a resource
+  prompts                        list the prompts
+  prompt <name> [key=value ...]  render a prompt
+  save <n>|all [path]            save content item n, or every non-text item, of the last result
+  help                           show this help
+  exit                           leave the shell
+
+Tab completes commands, tool names, argument keys, resource URIs and prompt names.
+Ctrl-C interrupts a running call, Ctrl-C or Ctrl-D on an empty line leaves the shell.
+`
+
+var shellCommands = []string{"tools", "schema", "call", "resources", "read", "prompts", "prompt", "save", "help", "exit", "quit"}
+
+// shellSession the state of an interactive shell
+type shellSession struct {
+	*app
+	conn    *mcpclient.Conn
+	input   lineReader
+	saveDir string
+
+	tools     []*mcpclient.Tool
+	resources []string
+	prompts   map[string][]string
+	// last is the result of the last call, its content items can be saved
+	last     *mcpclient.ToolResult
+	lastTool string
+}
+
+// shell connects once and runs commands typed interactively
+func shell(a *app, args []string) error {
+	flags := a.commandFlags("shell")
+	historyPath := flags.String("history", defaultHistoryPath(), "history file, empty keeps the history in memory only")
+	saveDir := flags.String("save-dir", ".", "directory content items are saved to when save is given no path")
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usagef("shell takes no arguments")
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	s := &shellSession{app: a, conn: conn, saveDir: *saveDir, prompts: make(map[string][]string)}
+	s.refresh(ctx)
+
+	s.input, err = newLineReader(s.complete, *historyPath)
+	if err != nil {
+		return err
+	}
+	defer s.input.Close()
+
+	// Ctrl-C interrupts the running call instead of ending the process
+	signal.Ignore(os.Interrupt)
+	fmt.Printf("Connected to %s %s over %s, type help for the commands\n",
+		conn.ServerInfo.Name, conn.ServerInfo.Version, a.server.Transport)
+	for {
+		line, err := s.input.ReadLine("mcp> ", true)
+		if errors.Is(err, errInterrupted) {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if name == "exit" || name == "quit" {
+			return nil
+		}
+		select {
+		case <-conn.Done():
+			return fmt.Errorf("connection lost")
+		default:
+		}
+		if err = s.run(ctx, name, strings.TrimSpace(rest)); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+	}
+}
+
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mcpcli_history")
+}
+
+// run executes a shell command, a call cancelled with Ctrl-C is reported as interrupted
+func (s *shellSession) run(ctx context.Context, name, rest string) error {
+	err := s.dispatch(ctx, name, rest)
+	if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+		return fmt.Errorf("interrupted")
+	}
+	return err
+}
+
+func (s *shellSession) dispatch(ctx context.Context, name, rest string) error {
+	switch name {
+	case "":
+		return nil
+	case "help":
+		fmt.Print(shellHelp)
+		return nil
+	case "tools":
+		return s.listTools(ctx)
+	case "schema":
+		return s.showSchema(rest)
+	case "call":
+		return s.call(ctx, rest)
+	case "resources":
+		return s.listResources(ctx)
+	case "read":
+		return s.read(ctx, rest)
+	case "prompts":
+		return s.listPrompts(ctx)
+	case "prompt":
+		return s.prompt(ctx, rest)
+	case "save":
+		return s.save(ctx, rest)
+	default:
+		return fmt.Errorf("unknown command %q, type help for the commands", name)
+	}
+}
+
+// refresh loads what completion offers, servers without resources or prompts simply offer none
+func (s *shellSession) refresh(ctx context.Context) {
+	if s.conn.Capabilities.Tools != nil {
+		if tools, err := s.conn.ListTools(ctx); err == nil {
+			s.tools = tools
+		}
+	}
+	if s.conn.Capabilities.Resources != nil {
+		if resources, err := s.conn.ListResources(ctx); err == nil {
+			s.resources = s.resources[:0]
+			for _, resource := range resources {
+				s.resources = append(s.resources, resource.URI)
+			}
+		}
+	}
+	if s.conn.Capabilities.Prompts != nil {
+		if prompts, err := s.conn.ListPrompts(ctx); err == nil {
+			clear(s.prompts)
+			for _, prompt := range prompts {
+				var arguments []string
+				for _, arg := range prompt.Arguments {
+					arguments = append(arguments, arg.Name)
+				}
+				s.prompts[prompt.Name] = arguments
+			}
+		}
+	}
+}
+
+// complete returns the candidates for the word following the words already typed
+func (s *shellSession) complete(typed string) []string {
+	words := strings.Fields(typed)
+	if len(words) == 0 {
+		return shellCommands
+	}
+
+	switch words[0] {
+	case "call", "schema":
+		if len(words) == 1 {
+			names := make([]string, 0, len(s.tools))
+			for _, tool := range s.tools {
+				names = append(names, tool.Name)
+			}
+			return names
+		}
+		if tool := s.tool(words[1]); tool != nil && words[0] == "call" {
+			return argumentKeys(parseSchema(tool.InputSchema).names(), words[2:])
+		}
+	case "read":
+		if len(words) == 1 {
+			return s.resources
+		}
+	case "prompt":
+		if len(words) == 1 {
+			names := make([]string, 0, len(s.prompts))
+			for name := range s.prompts {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			return names
+		}
+		return argumentKeys(s.prompts[words[1]], words[2:])
+	case "save":
+		if len(words) == 1 && s.last != nil {
+			candidates := []string{"all"}
+			for i := range s.last.Content {
+				candidates = append(candidates, strconv.Itoa(i+1))
+			}
+			return candidates
+		}
+	}
+	return nil
+}
+
+// argumentKeys offers "key=" for the keys not given yet
+func argumentKeys(keys []string, given []string) []string {
+	var candidates []string
+	for _, key := range keys {
+		if !slices.ContainsFunc(given, func(arg string) bool { return strings.HasPrefix(arg, key+"=") }) {
+			candidates = append(candidates, key+"=")
+		}
+	}
+	return candidates
+}
+
+func (s *shellSession) tool(name string) *mcpclient.Tool {
+	for _, tool := range s.tools {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+func (s *shellSession) listTools(ctx context.Context) error {
+	tools, err := s.conn.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+	s.tools = tools
+	rows := make([][]string, 0, len(tools))
+	for _, tool := range tools {
+		rows = append(rows, []string{tool.Name, strings.Join(schemaArguments(tool.InputSchema), " "), summary(tool.Description)})
+	}
+	return s.output.table([]string{"NAME", "ARGUMENTS", "DESCRIPTION"}, rows)
+}
+
+func (s *shellSession) showSchema(name string) error {
+	tool := s.tool(name)
+	if tool == nil {
+		return fmt.Errorf("unknown tool %q", name)
+	}
+	if tool.Description != "" {
+		fmt.Println(tool.Description)
+	}
+	return s.output.json(tool.InputSchema)
+}
+
+func (s *shellSession) call(ctx context.Context, rest string) error {
+	name, rawArgs, _ := strings.Cut(rest, " ")
+	if name == "" {
+		return fmt.Errorf("usage: call <tool> [key=value ...]")
+	}
+	tool := s.tool(name)
+	if tool == nil {
+		// The list may be stale, the server decides
+		tool = &mcpclient.Tool{Name: name}
+	}
+
+	arguments, err := s.toolArguments(parseSchema(tool.InputSchema), strings.TrimSpace(rawArgs))
+	if err != nil {
+		return err
+	}
+	callCtx, stop := s.input.Interruptible(ctx)
+	result, err := s.conn.CallTool(callCtx, name, arguments, func(notify *protocol.ProgressNotification) {
+		fmt.Fprint(os.Stderr, progressLine(notify)+s.input.EOL())
+	})
+	stop()
+	if err != nil {
+		return err
+	}
+	s.last, s.lastTool = result, name
+	s.showResult(result)
+	return nil
+}
+
+// toolArguments builds the arguments of a call from a JSON object or key=value pairs,
+// then asks for the required ones still missing
+func (s *shellSession) toolArguments(sch *schema, input string) (json.RawMessage, error) {
+	arguments := make(map[string]json.RawMessage)
+	if strings.HasPrefix(input, "{") {
+		if err := json.Unmarshal([]byte(input), &arguments); err != nil {
+			return nil, fmt.Errorf("invalid JSON arguments: %v", err)
+		}
+	} else {
+		pairs, err := splitPairs(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			prop, ok := sch.Properties[pair[0]]
+			if !ok {
+				if len(sch.Properties) > 0 {
+					return nil, fmt.Errorf("unknown argument %q, expected one of %s", pair[0], strings.Join(sch.names(), ", "))
+				}
+				prop = &property{}
+			}
+			value, err := prop.parse(pair[1])
+			if err != nil {
+				return nil, fmt.Errorf("argument %s: %v", pair[0], err)
+			}
+			arguments[pair[0]] = value
+		}
+	}
+
+	for _, name := range sch.Required {
+		if _, ok := arguments[name]; ok {
+			continue
+		}
+		prop, ok := sch.Properties[name]
+		if !ok {
+			prop = &property{}
+		}
+		value, err := s.ask(name, prop)
+		if err != nil {
+			return nil, err
+		}
+		arguments[name] = value
+	}
+	return json.Marshal(arguments)
+}
+
+// ask prompts for a required field until a valid value is typed
+func (s *shellSession) ask(name string, prop *property) (json.RawMessage, error) {
+	prompt := name
+	if description := prop.describe(); description != "" {
+		prompt += " (" + description + ")"
+	}
+	for {
+		input, err := s.input.ReadLine(prompt+": ", false)
+		if err != nil {
+			if errors.Is(err, errInterrupted) {
+				return nil, fmt.Errorf("call aborted")
+			}
+			return nil, err
+		}
+		if input == "" {
+			fmt.Fprintf(os.Stderr, "%s is required\n", name)
+			continue
+		}
+		value, err := prop.parse(input)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		return value, nil
+	}
+}
+
+// splitPairs splits key=value pairs, values may be double or single quoted to contain spaces
+func splitPairs(input string) ([][2]string, error) {
+	var (
+		pairs   [][2]string
+		current strings.Builder
+		quote   rune
+		words   []string
+		inWord  bool
+	)
+	for _, r := range input {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+
+	for _, word := range words {
+		key, value, ok := strings.Cut(word, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid argument %q, expected key=value", word)
+		}
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return pairs, nil
+}
+
+// showResult pretty-prints a tool result, JSON text is indented and binary items are numbered for save
+func (s *shellSession) showResult(result *mcpclient.ToolResult) {
+	if result.IsError {
+		fmt.Println("tool returned an error:")
+	}
+	for i, item := range result.Content {
+		switch item.Type {
+		case "text":
+			fmt.Println(indentJSON(item.Text))
+		case "image", "audio":
+			fmt.Printf("[%d] %s %s, %s, save %d to write it to disk\n", i+1, item.Type, item.MimeType, dataSize(item.Data), i+1)
+		case "resource_link":
+			fmt.Printf("[%d] resource link %s %s, save %d to fetch it to disk\n", i+1, item.URI, item.Name, i+1)
+		case "resource":
+			if item.Resource != nil && item.Resource.Blob != "" {
+				fmt.Printf("[%d] resource %s %s, %s, save %d to write it to disk\n",
+					i+1, item.Resource.URI, item.Resource.MimeType, dataSize(item.Resource.Blob), i+1)
+			} else if item.Resource != nil {
+				fmt.Printf("[%d] resource %s:\n%s\n", i+1, item.Resource.URI, indentJSON(item.Resource.Text))
+			}
+		default:
+			fmt.Printf("[%d] %s content\n", i+1, item.Type)
+		}
+	}
+	if len(result.StructuredContent) > 0 {
+		fmt.Println("structured content:")
+		fmt.Println(indentJSON(string(result.StructuredContent)))
+	}
+}
+
+// indentJSON indents text holding a JSON object or array and returns any other text unchanged
+func indentJSON(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return text
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(trimmed), "", "  "); err != nil {
+		return text
+	}
+	return buf.String()
+}
+
+// save writes content items of the last result to disk, linked resources are read from the server
+func (s *shellSession) save(ctx context.Context, rest string) error {
+	if s.last == nil {
+		return fmt.Errorf("no result to save, call a tool first")
+	}
+	which, path, _ := strings.Cut(rest, " ")
+	path = strings.TrimSpace(path)
+
+	if which == "all" {
+		dir := s.saveDir
+		if path != "" {
+			dir = path
+		}
+		saved, err := s.conn.Save(ctx, s.last.Content, dir, s.lastTool)
+		printSaved(os.Stdout, saved)
+		if len(saved) == 0 && err == nil {
+			return fmt.Errorf("the last result has no image, audio or resource")
+		}
+		return err
+	}
+
+	n, err := strconv.Atoi(which)
+	if err != nil || n < 1 || n > len(s.last.Content) {
+		return fmt.Errorf("usage: save <n>|all [path], n between 1 and %d", len(s.last.Content))
+	}
+	return s.saveItem(ctx, n, path)
+}
+
+// saveItem writes item n to path, or to the save directory when path is empty.
+// A resource with several contents is written to numbered files.
+func (s *shellSession) saveItem(ctx context.Context, n int, path string) error {
+	item := s.last.Content[n-1]
+	var files []mcpclient.File
+	if item.Type == "text" {
+		files = []mcpclient.File{{MimeType: "text/plain", Data: []byte(item.Text)}}
+	} else {
+		var err error
+		if files, err = s.conn.Files(ctx, item); err != nil {
+			return err
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("item %d is a %s and has no data to save", n, item.Type)
+	}
+
+	for i, file := range files {
+		target := path
+		if target == "" {
+			target = filepath.Join(s.saveDir, fmt.Sprintf("%s_%d%s", s.lastTool, n, file.Ext()))
+		}
+		if len(files) > 1 {
+			ext := filepath.Ext(target)
+			target = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(target, ext), i+1, ext)
+		}
+		if err := mcpclient.WriteFile(target, file.Data); err != nil {
+			return err
+		}
+		printSaved(os.Stdout, []mcpclient.SavedFile{{Item: n, Type: item.Type, URI: file.URI, MimeType: file.MimeType,
+			Path: target, Size: len(file.Data)}})
+	}
+	return nil
+}
+
+func (s *shellSession) listResources(ctx context.Context) error {
+	resources, err := s.conn.ListResources(ctx)
+	if err != nil {
+		return err
+	}
+	s.resources = s.resources[:0]
+	rows := make([][]string, 0, len(resources))
+	for _, resource := range resources {
+		s.resources = append(s.resources, resource.URI)
+		rows = append(rows, []string{resource.URI, resource.Name, resource.MimeType, summary(resource.Description)})
+	}
+	return s.output.table([]string{"URI", "NAME", "MIME TYPE", "DESCRIPTION"}, rows)
+}
+
+func (s *shellSession) read(ctx context.Context, uri string) error {
+	if uri == "" {
+		return fmt.Errorf("usage: read <uri>")
+	}
+	contents, err := s.conn.ReadResource(ctx, uri)
+	if err != nil {
+		return err
+	}
+	// Read contents can be saved like the items of a tool result
+	result := &mcpclient.ToolResult{}
+	for i := range contents {
+		result.Content = append(result.Content, mcpclient.Content{Type: "resource", Resource: &contents[i]})
+	}
+	s.last, s.lastTool = result, "resource"
+	s.showResult(result)
+	return nil
+}
+
+func (s *shellSession) listPrompts(ctx context.Context) error {
+	prompts, err := s.conn.ListPrompts(ctx)
+	if err != nil {
+		return err
+	}
+	clear(s.prompts)
+	rows := make([][]string, 0, len(prompts))
+	for _, prompt := range prompts {
+		var arguments, display []string
+		for _, arg := range prompt.Arguments {
+			arguments = append(arguments, arg.Name)
+			if arg.Required {
+				display = append(display, arg.Name)
+			} else {
+				display = append(display, "["+arg.Name+"]")
+			}
+		}
+		s.prompts[prompt.Name] = arguments
+		rows = append(rows, []string{prompt.Name, strings.Join(display, " "), summary(prompt.Description)})
+	}
+	return s.output.table([]string{"NAME", "ARGUMENTS", "DESCRIPTION"}, rows)
+}
+
+func (s *shellSession) prompt(ctx context.Context, rest string) error {
+	name, rawArgs, _ := strings.Cut(rest, " ")
+	if name == "" {
+		return fmt.Errorf("usage: prompt <name> [key=value ...]")
+	}
+	pairs, err := splitPairs(strings.TrimSpace(rawArgs))
+	if err != nil {
+		return err
+	}
+	arguments := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		arguments[pair[0]] = pair[1]
+	}
+
+	result, err := s.conn.GetPrompt(ctx, name, arguments)
+	if err != nil {
+		return err
+	}
+	if result.Description != "" {
+		fmt.Printf("# %s\n", result.Description)
+	}
+	for _, message := range result.Messages {
+		fmt.Printf("[%s]\n", message.Role)
+		s.output.content([]mcpclient.Content{message.Content})
+	}
+	return nil
+}
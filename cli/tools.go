@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mcp/mcpclient"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+func toolsList(a *app, args []string) error {
+	flags := a.commandFlags("tools list")
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usagef("tools list takes no arguments")
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	tools, err := conn.ListTools(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tools: %v", err)
+	}
+
+	if a.output.isJSON() {
+		return a.output.json(tools)
+	}
+	rows := make([][]string, 0, len(tools))
+	for _, tool := range tools {
+		rows = append(rows, []string{tool.Name, strings.Join(schemaArguments(tool.InputSchema), " "), summary(tool.Description)})
+	}
+	return a.output.table([]string{"NAME", "ARGUMENTS", "DESCRIPTION"}, rows)
+}
+
+func toolsCall(a *app, args []string) error {
+	flags := a.commandFlags("tools call")
+	rawArgs := flags.String("args", "", "tool arguments as a JSON object, @file reads them from a file and @- from stdin")
+	saveDir := flags.String("save-dir", "", "write image, audio and resource items to this directory, reading linked resources")
+	ctx, positional, err := a.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usagef("tools call takes exactly one tool name")
+	}
+	arguments, err := readArguments(*rawArgs)
+	if err != nil {
+		return usagef("invalid -args: %v", err)
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := conn.CallTool(ctx, positional[0], arguments, printProgress)
+	if err != nil {
+		return fmt.Errorf("failed to call tool %s: %v", positional[0], err)
+	}
+
+	if a.output.isJSON() {
+		err = a.output.json(result)
+	} else {
+		a.output.content(result.Content)
+		if len(result.Content) == 0 && len(result.StructuredContent) > 0 {
+			err = a.output.json(result.StructuredContent)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if *saveDir != "" {
+		saved, err := conn.Save(ctx, result.Content, *saveDir, positional[0])
+		// The JSON output stays a single document, the summary goes to stderr
+		w := os.Stdout
+		if a.output.isJSON() {
+			w = os.Stderr
+		}
+		printSaved(w, saved)
+		if err != nil {
+			return fmt.Errorf("failed to save result: %v", err)
+		}
+	}
+	if result.IsError {
+		return errToolFailed
+	}
+	return nil
+}
+
+// printSaved prints a line per saved file
+func printSaved(w io.Writer, saved []mcpclient.SavedFile) {
+	for _, file := range saved {
+		source := file.Type
+		if file.URI != "" {
+			source += " " + file.URI
+		}
+		fmt.Fprintf(w, "saved item %d (%s) to %s, %d bytes\n", file.Item, source, file.Path, file.Size)
+	}
+}
+
+// readArguments reads the -args value: inline JSON, @file or @- for stdin. It must be a JSON object.
+func readArguments(value string) (json.RawMessage, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data := []byte(value)
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		var err error
+		if path == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("expected a JSON object: %v", err)
+	}
+	// null decodes without error into a nil map
+	if object == nil {
+		return nil, fmt.Errorf("expected a JSON object, got %s", bytes.TrimSpace(data))
+	}
+	return json.RawMessage(data), nil
+}
+
+// schemaArguments lists the properties of an input schema, optional ones in brackets
+func schemaArguments(raw json.RawMessage) []string {
+	s := parseSchema(raw)
+	names := s.names()
+	for i, name := range names {
+		if !slices.Contains(s.Required, name) {
+			names[i] = "[" + name + "]"
+		}
+	}
+	return names
+}
+
+// printProgress shows a progress notification on stderr, stdout only carries the result
+func printProgress(notify *protocol.ProgressNotification) {
+	fmt.Fprintln(os.Stderr, progressLine(notify))
+}
+
+func progressLine(notify *protocol.ProgressNotification) string {
+	progress := fmt.Sprintf("%g", notify.Progress)
+	if notify.Total > 0 {
+		progress += fmt.Sprintf("/%g", notify.Total)
+	}
+	if notify.Message != "" {
+		progress += " " + notify.Message
+	}
+	return "progress: " + progress
+}
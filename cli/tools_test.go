@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadArguments(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "args.json")
+	if err := os.WriteFile(file, []byte(`{"text": "from file"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"", "", true},
+		{`{"text": "hello"}`, `{"text": "hello"}`, true},
+		{"@" + file, `{"text": "from file"}`, true},
+		{"null", "", false},
+		{" null ", "", false},
+		{`["text"]`, "", false},
+		{`"text"`, "", false},
+		{"{", "", false},
+		{"@" + file + ".missing", "", false},
+	} {
+		got, err := readArguments(tt.value)
+		if (err == nil) != tt.ok || string(got) != tt.want {
+			t.Errorf("readArguments(%q) = %s, %v", tt.value, got, err)
+		}
+	}
+}
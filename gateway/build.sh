@@ -0,0 +1 @@
+go build -v -o mcpGateway .
\ No newline at end of file
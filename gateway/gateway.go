@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mcp/mcpclient"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+)
+
+// How a tool or prompt name offered by several upstreams is resolved
+const (
+	// CollisionPrefix exposes the later one as "<upstream>_<name>"
+	CollisionPrefix = "prefix"
+	// CollisionFirst keeps the one of the first upstream and skips the others
+	CollisionFirst = "first"
+	// CollisionError refuses to start
+	CollisionError = "error"
+)
+
+// Upstream an MCP server fronted by the gateway
+type Upstream struct {
+	Name string
+	// Prefix is prepended to the names of its tools and prompts
+	Prefix string
+	Conn   *mcpclient.Conn
+}
+
+// Gateway registers proxies of the upstream tools, prompts and resources on the server
+type Gateway struct {
+	Server    *server.Server
+	Collision string
+
+	// exposed name or URI -> upstream name
+	tools     map[string]string
+	prompts   map[string]string
+	resources map[string]string
+	templates map[string]string
+}
+
+// NewGateway creates a gateway registering on srv
+func NewGateway(srv *server.Server, collision string) (*Gateway, error) {
+	switch collision {
+	case "":
+		collision = CollisionPrefix
+	case CollisionPrefix, CollisionFirst, CollisionError:
+	default:
+		return nil, fmt.Errorf("unknown collision handling %q, expected prefix, first or error", collision)
+	}
+	return &Gateway{
+		Server:    srv,
+		Collision: collision,
+		tools:     make(map[string]string),
+		prompts:   make(map[string]string),
+		resources: make(map[string]string),
+		templates: make(map[string]string),
+	}, nil
+}
+
+// Add registers everything the upstream offers
+func (g *Gateway) Add(ctx context.Context, up *Upstream) error {
+	capabilities := up.Conn.Capabilities
+	if capabilities.Tools != nil {
+		if err := g.addTools(ctx, up); err != nil {
+			return err
+		}
+	}
+	if capabilities.Prompts != nil {
+		if err := g.addPrompts(ctx, up); err != nil {
+			return err
+		}
+	}
+	if capabilities.Resources != nil {
+		if err := g.addResources(ctx, up); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Gateway) addTools(ctx context.Context, up *Upstream) error {
+	tools, err := up.Conn.ListTools(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tools of %s: %v", up.Name, err)
+	}
+	for _, tool := range tools {
+		name, ok, err := g.exposedName(g.tools, "tool", up, tool.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		proxied := tool.ToProtocol()
+		proxied.Name = name
+		g.Server.RegisterTool(proxied, g.toolHandler(up, tool.Name))
+		g.tools[name] = up.Name
+	}
+	slog.Info("Registered upstream tools", "upstream", up.Name, "count", len(tools))
+	return nil
+}
+
+func (g *Gateway) addPrompts(ctx context.Context, up *Upstream) error {
+	prompts, err := up.Conn.ListPrompts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list prompts of %s: %v", up.Name, err)
+	}
+	for _, prompt := range prompts {
+		name, ok, err := g.exposedName(g.prompts, "prompt", up, prompt.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		original := prompt.Name
+		proxied := *prompt
+		proxied.Name = name
+		g.Server.RegisterPrompt(&proxied, func(ctx context.Context, req *protocol.GetPromptRequest) (*protocol.GetPromptResult, error) {
+			result, err := up.Conn.GetPrompt(ctx, original, req.Arguments)
+			if err != nil {
+				return nil, fmt.Errorf("upstream %s: %v", up.Name, err)
+			}
+			return result.ToProtocol()
+		})
+		g.prompts[name] = up.Name
+	}
+	return nil
+}
+
+// addResources registers the resources and resource templates under their upstream URIs,
+// URIs cannot be prefixed so a URI offered twice is always served by the first upstream
+func (g *Gateway) addResources(ctx context.Context, up *Upstream) error {
+	read := func(ctx context.Context, req *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error) {
+		contents, err := up.Conn.ReadResource(ctx, req.URI)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: %v", up.Name, err)
+		}
+		result := &protocol.ReadResourceResult{Contents: make([]protocol.ResourceContents, 0, len(contents))}
+		for _, c := range contents {
+			resource, err := c.ToProtocol()
+			if err != nil {
+				return nil, fmt.Errorf("upstream %s: %v", up.Name, err)
+			}
+			result.Contents = append(result.Contents, resource)
+		}
+		return result, nil
+	}
+
+	resources, err := up.Conn.ListResources(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list resources of %s: %v", up.Name, err)
+	}
+	for _, resource := range resources {
+		if ok, err := g.claimURI(g.resources, "resource", up, resource.URI); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		g.Server.RegisterResource(resource, read)
+	}
+
+	templates, err := up.Conn.ListResourceTemplates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list resource templates of %s: %v", up.Name, err)
+	}
+	for _, template := range templates {
+		if ok, err := g.claimURI(g.templates, "resource template", up, template.URITemplate); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if err = g.Server.RegisterResourceTemplate(template, read); err != nil {
+			return fmt.Errorf("failed to register resource template %s of %s: %v", template.URITemplate, up.Name, err)
+		}
+	}
+	return nil
+}
+
+// toolHandler forwards a call to the upstream tool, with its progress notifications
+func (g *Gateway) toolHandler(up *Upstream, name string) server.ToolHandlerFunc {
+	return func(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		var onProgress func(*protocol.ProgressNotification)
+		if _, ok := req.Meta[protocol.ProgressTokenKey]; ok {
+			onProgress = func(notify *protocol.ProgressNotification) {
+				if err := g.Server.SendProgressNotification(ctx, notify); err != nil {
+					slog.DebugContext(ctx, "Failed to forward progress notification", "tool", req.Name, "error", err)
+				}
+			}
+		}
+
+		result, err := up.Conn.CallTool(ctx, name, req.RawArguments, onProgress)
+		if err != nil {
+			slog.WarnContext(ctx, "Upstream tool call failed", "upstream", up.Name, "tool", name, "error", err)
+			return nil, fmt.Errorf("upstream %s: %v", up.Name, err)
+		}
+		return result.ToProtocol()
+	}
+}
+
+// exposedName returns the name a tool or prompt of the upstream is exposed under,
+// ok is false when it is skipped because another upstream already provides it
+func (g *Gateway) exposedName(registered map[string]string, kind string, up *Upstream, name string) (_ string, ok bool, _ error) {
+	exposed := up.Prefix + name
+	owner, taken := registered[exposed]
+	if !taken {
+		return exposed, true, nil
+	}
+
+	switch g.Collision {
+	case CollisionError:
+		return "", false, fmt.Errorf("%s %q of %s is already provided by %s", kind, exposed, up.Name, owner)
+	case CollisionFirst:
+		slog.Warn("Skipping duplicate "+kind, "name", exposed, "upstream", up.Name, "provided_by", owner)
+		return "", false, nil
+	}
+
+	renamed := up.Name + "_" + exposed
+	if owner, taken = registered[renamed]; taken {
+		return "", false, fmt.Errorf("%s %q of %s is already provided by %s", kind, renamed, up.Name, owner)
+	}
+	slog.Warn("Renamed duplicate "+kind, "name", exposed, "upstream", up.Name, "exposed_as", renamed, "provided_by", registered[exposed])
+	return renamed, true, nil
+}
+
+// claimURI records the upstream serving a resource URI, ok is false when another upstream already serves it
+func (g *Gateway) claimURI(registered map[string]string, kind string, up *Upstream, uri string) (ok bool, _ error) {
+	if owner, taken := registered[uri]; taken {
+		if g.Collision == CollisionError {
+			return false, fmt.Errorf("%s %q of %s is already provided by %s", kind, uri, up.Name, owner)
+		}
+		slog.Warn("Skipping duplicate "+kind, "uri", uri, "upstream", up.Name, "provided_by", owner)
+		return false, nil
+	}
+	registered[uri] = up.Name
+	return true, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mcp/logging"
+	"mcp/mcpclient"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+	"gopkg.in/ini.v1"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run starts the gateway and returns the exit code once it stopped, so that the upstream
+// connections are closed and the logs flushed on every exit
+func run() int {
+	// Load configuration from ini file
+	cfg, err := ini.Load("config.ini")
+	if err != nil {
+		return failed("Failed to load config file", "error", err)
+	}
+
+	gatewaySection := cfg.Section("Gateway")
+	mode := gatewaySection.Key("Mode").MustString(mcpclient.Stdio)
+	addr := gatewaySection.Key("Addr").MustString("127.0.0.1:8090")
+
+	// Structured logging, stdout is reserved for MCP messages in stdio mode
+	logger, logCloser, err := logging.New(logging.Config{
+		Level:  cfg.Section("Log").Key("Level").String(),
+		Format: cfg.Section("Log").Key("Format").String(),
+		Output: cfg.Section("Log").Key("Output").String(),
+		Stdio:  mode == mcpclient.Stdio,
+	})
+	if err != nil {
+		return failed("Failed to set up logging", "error", err)
+	}
+	defer logCloser.Close()
+	slog.SetDefault(logger)
+	sdkLogger := logging.SDKLogger(logger)
+
+	// Create MCP transport layer
+	var (
+		transportServer transport.ServerTransport
+		mux             *http.ServeMux
+	)
+	switch mode {
+	case mcpclient.SSE:
+		var handler *transport.SSEHandler
+		transportServer, handler, err = transport.NewSSEServerTransportAndHandler("/message",
+			transport.WithSSEServerTransportAndHandlerOptionLogger(sdkLogger))
+		if err != nil {
+			return failed("Failed to create SSE transport", "error", err)
+		}
+		mux = http.NewServeMux()
+		mux.Handle("/sse", handler.HandleSSE())
+		mux.Handle("/message", handler.HandleMessage())
+	case mcpclient.Streamable:
+		var handler *transport.StreamableHTTPHandler
+		transportServer, handler, err = transport.NewStreamableHTTPServerTransportAndHandler(
+			transport.WithStreamableHTTPServerTransportAndHandlerOptionStateMode(transport.Stateful),
+			transport.WithStreamableHTTPServerTransportAndHandlerOptionLogger(sdkLogger))
+		if err != nil {
+			return failed("Failed to create streamable HTTP transport", "error", err)
+		}
+		mux = http.NewServeMux()
+		mux.Handle("/mcp", handler.HandleMCP())
+	case mcpclient.Stdio:
+		transportServer = transport.NewStdioServerTransport(transport.WithStdioServerOptionLogger(sdkLogger))
+	default:
+		return failed("Unknown Gateway.Mode, expected stdio, sse or streamable", "mode", mode)
+	}
+
+	mcpServer, err := server.NewServer(transportServer,
+		server.WithServerInfo(protocol.Implementation{
+			Name:    gatewaySection.Key("Name").MustString("MCP Gateway"),
+			Version: "1.0.0",
+		}),
+		server.WithLogger(sdkLogger),
+	)
+	if err != nil {
+		return failed("Failed to create MCP server", "error", err)
+	}
+
+	gateway, err := NewGateway(mcpServer, gatewaySection.Key("Collision").String())
+	if err != nil {
+		return failed("Invalid gateway configuration", "error", err)
+	}
+
+	// Connect to the upstream servers in configuration order, unreachable ones are skipped
+	var upstreams []*Upstream
+	defer func() {
+		for _, up := range upstreams {
+			up.Conn.Close()
+		}
+	}()
+	for _, section := range cfg.Section("Upstream").ChildSections() {
+		name := strings.TrimPrefix(section.Name(), "Upstream.")
+		upstreamCfg, err := upstreamConfig(section)
+		if err != nil {
+			return failed("Invalid upstream configuration", "upstream", name, "error", err)
+		}
+
+		conn, err := mcpclient.Dial(context.Background(), upstreamCfg, sdkLogger, protocol.Implementation{
+			Name:    "MCP Gateway",
+			Version: "1.0.0",
+		})
+		if err != nil {
+			slog.Error("Skipping unreachable upstream", "upstream", name, "error", err)
+			continue
+		}
+		up := &Upstream{Name: name, Prefix: section.Key("Prefix").String(), Conn: conn}
+		if err = gateway.Add(context.Background(), up); err != nil {
+			conn.Close()
+			return failed("Failed to register upstream", "upstream", name, "error", err)
+		}
+		upstreams = append(upstreams, up)
+		slog.Info("Connected upstream", "upstream", name, "server", conn.ServerInfo.Name)
+	}
+	if len(upstreams) == 0 {
+		return failed("No upstream server reachable, configure at least one [Upstream.<name>] section")
+	}
+
+	var httpServer *http.Server
+	serveErr := make(chan error, 1)
+	if mux != nil {
+		httpServer = serveHTTP(addr, mux, serveErr)
+	}
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- mcpServer.Run()
+	}()
+
+	exitCode := 0
+	select {
+	case err = <-runErr:
+		if err != nil {
+			return failed("Failed to run server", "error", err)
+		}
+		return 0
+	case err = <-serveErr:
+		slog.Error("HTTP server failed, shutting down", "error", err)
+		exitCode = 1
+	case <-signalCtx.Done():
+	}
+	stopSignals()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	if err = mcpServer.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Failed to shut down MCP server", "error", err)
+	}
+	if httpServer != nil {
+		if err = httpServer.Shutdown(shutdownCtx); err != nil {
+			httpServer.Close()
+		}
+	}
+	slog.Info("Gateway stopped")
+	return exitCode
+}
+
+// upstreamConfig reads an [Upstream.<name>] section.
+// Header.<Name> keys are sent as HTTP headers, e.g. Header.Authorization = Bearer xxx
+func upstreamConfig(section *ini.Section) (mcpclient.Config, error) {
+	cfg := mcpclient.Config{
+		Transport: section.Key("Transport").String(),
+		URL:       section.Key("URL").String(),
+		Command:   section.Key("Command").String(),
+		Args:      strings.Fields(section.Key("Args").String()),
+		Env:       section.Key("Env").Strings(","),
+		Header:    make(http.Header),
+		Timeout:   section.Key("Timeout").MustDuration(0),
+	}
+	for _, key := range section.Keys() {
+		if name, ok := strings.CutPrefix(key.Name(), "Header."); ok {
+			cfg.Header.Add(name, key.String())
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// serveHTTP starts serving handler on addr, the failure of the listener is sent to errs
+func serveHTTP(addr string, handler http.Handler, errs chan<- error) *http.Server {
+	slog.Info("Starting HTTP server", "addr", addr)
+	httpServer := &http.Server{
+		Addr:        addr,
+		Handler:     handler,
+		IdleTimeout: time.Minute,
+	}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("listening on %s: %v", addr, err)
+		}
+	}()
+	return httpServer
+}
+
+// failed logs an error and returns the exit code of a failed start
+func failed(msg string, args ...any) int {
+	slog.Error(msg, args...)
+	return 1
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Output destinations
+const (
+	OutputStderr = "stderr"
+	OutputStdout = "stdout"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config logging configuration
+type Config struct {
+	// Level is one of debug, info, warn or error
+	Level string
+	// Format is text or json
+	Format string
+	// Output is stderr, stdout or a file path
+	Output string
+	// Stdio must be set when the MCP server speaks over stdio, stdout then carries protocol messages
+	Stdio bool
+	// Secrets are literal values such as API keys that are always redacted
+	Secrets []string
+}
+
+// New builds a redacting slog logger from the configuration.
+// The returned closer releases the log file, it is a no-op for stderr and stdout.
+func New(cfg Config) (*slog.Logger, io.Closer, error) {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		out    io.Writer
+		closer io.Closer = nopCloser{}
+	)
+	switch strings.ToLower(cfg.Output) {
+	case "", OutputStderr:
+		out = os.Stderr
+	case OutputStdout:
+		if cfg.Stdio {
+			return nil, nil, fmt.Errorf("log output stdout is not allowed in stdio mode, it carries MCP messages")
+		}
+		out = os.Stdout
+	default:
+		file, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %v", err)
+		}
+		out, closer = file, file
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(out, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(out, opts)
+	default:
+		return nil, nil, fmt.Errorf("unknown log format %q, expected text or json", cfg.Format)
+	}
+
+	handler = newRedactHandler(newContextHandler(handler), NewRedactor(cfg.Secrets...))
+	return slog.New(handler), closer, nil
+}
+
+func parseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", level)
+	}
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
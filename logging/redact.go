@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	bearerPattern     = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=\-]+`)
+	dataURIPattern    = regexp.MustCompile(`data:[\w.+\-]+/[\w.+\-]+;base64,[A-Za-z0-9+/=]+`)
+	longBlobPattern   = regexp.MustCompile(`[A-Za-z0-9+/=]{256,}`)
+	jsonSecretPattern = regexp.MustCompile(`(?i)("(?:api_?key|authorization|token|access_token|secret|password)"\s*:\s*")[^"]*(")`)
+	querySecretRegexp = regexp.MustCompile(`(?i)([?&](?:key|api_?key|token|access_token)=)[^&\s"]+`)
+)
+
+// sensitiveKeys attribute keys whose values are always redacted
+var sensitiveKeys = map[string]struct{}{
+	"api_key":       {},
+	"apikey":        {},
+	"authorization": {},
+	"token":         {},
+	"access_token":  {},
+	"secret":        {},
+	"password":      {},
+}
+
+// Redactor removes secrets and bulky base64 payloads from strings
+type Redactor struct {
+	secrets []string
+}
+
+// NewRedactor creates a redactor that additionally masks the given literal secrets
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+	return r
+}
+
+// String redacts API keys, bearer tokens, secret query parameters and base64 payloads
+func (r *Redactor) String(s string) string {
+	s = r.Secrets(s)
+	s = dataURIPattern.ReplaceAllStringFunc(s, func(match string) string {
+		prefix, payload, _ := strings.Cut(match, ",")
+		return fmt.Sprintf("%s,[%d chars redacted]", prefix, len(payload))
+	})
+	s = longBlobPattern.ReplaceAllStringFunc(s, func(match string) string {
+		return fmt.Sprintf("[%d chars redacted]", len(match))
+	})
+	return s
+}
+
+// Secrets redacts API keys, bearer tokens and secret query parameters but keeps payloads intact
+func (r *Redactor) Secrets(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	s = bearerPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = jsonSecretPattern.ReplaceAllString(s, "${1}"+redacted+"${2}")
+	return querySecretRegexp.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// Attr redacts a log attribute, recursing into groups
+func (r *Redactor) Attr(a slog.Attr) slog.Attr {
+	if _, ok := sensitiveKeys[strings.ToLower(a.Key)]; ok {
+		return slog.String(a.Key, redacted)
+	}
+
+	value := a.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.String(value.String()))
+	case slog.KindGroup:
+		attrs := value.Group()
+		redactedAttrs := make([]any, len(attrs))
+		for i, attr := range attrs {
+			redactedAttrs[i] = r.Attr(attr)
+		}
+		return slog.Group(a.Key, redactedAttrs...)
+	case slog.KindAny:
+		// Errors, maps and structs are rendered as text so their content can be inspected
+		return slog.String(a.Key, r.String(fmt.Sprint(value.Any())))
+	default:
+		return slog.Attr{Key: a.Key, Value: value}
+	}
+}
+
+// redactHandler applies a Redactor to messages and attributes before they reach the wrapped handler
+type redactHandler struct {
+	next     slog.Handler
+	redactor *Redactor
+}
+
+func newRedactHandler(next slog.Handler, redactor *Redactor) slog.Handler {
+	return &redactHandler{next: next, redactor: redactor}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, record slog.Record) error {
+	clean := slog.NewRecord(record.Time, record.Level, h.redactor.String(record.Message), record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(h.redactor.Attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = h.redactor.Attr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(clean), redactor: h.redactor}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
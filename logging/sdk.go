@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+// sdkLogger adapts a slog logger to the go-mcp logger interface
+type sdkLogger struct {
+	logger *slog.Logger
+}
+
+// SDKLogger returns a go-mcp logger writing to the given slog logger
+func SDKLogger(logger *slog.Logger) pkg.Logger {
+	return &sdkLogger{logger: logger.With("component", "go-mcp")}
+}
+
+func (l *sdkLogger) Debugf(format string, a ...any) {
+	l.logger.Log(context.Background(), slog.LevelDebug, fmt.Sprintf(format, a...))
+}
+
+func (l *sdkLogger) Infof(format string, a ...any) {
+	l.logger.Log(context.Background(), slog.LevelInfo, fmt.Sprintf(format, a...))
+}
+
+func (l *sdkLogger) Warnf(format string, a ...any) {
+	l.logger.Log(context.Background(), slog.LevelWarn, fmt.Sprintf(format, a...))
+}
+
+func (l *sdkLogger) Errorf(format string, a ...any) {
+	l.logger.Log(context.Background(), slog.LevelError, fmt.Sprintf(format, a...))
+}
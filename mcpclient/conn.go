@@ -0,0 +1,367 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+)
+
+// ErrClosed is returned by requests on a closed connection or one dropped by the server
+var ErrClosed = errors.New("connection closed")
+
+// RPCError a JSON-RPC error returned by the server
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Conn an MCP client connection working on the JSON-RPC messages themselves.
+// The SDK client decodes every content item of a tool result as text, losing image, audio and
+// resource data, and only fetches the first page of lists. Conn keeps results as sent,
+// follows list cursors and reports the progress of any request.
+type Conn struct {
+	transport transport.ClientTransport
+	logger    pkg.Logger
+
+	// ServerInfo, Capabilities, ProtocolVersion and Instructions come from the initialize result
+	ServerInfo      protocol.Implementation
+	Capabilities    protocol.ServerCapabilities
+	ProtocolVersion string
+	Instructions    string
+
+	// OnNotification receives the server notifications other than progress, set it before Dial returns
+	OnNotification func(method protocol.Method, params json.RawMessage)
+
+	nextID atomic.Int64
+
+	mu      sync.Mutex
+	pending map[string]*pendingCall
+	// cancelled the requests cancelled by the client, until the server answers them anyway
+	cancelled map[string]bool
+	// lateAnswers counts the answers to cancelled requests
+	lateAnswers int
+	closeErr    error
+	done        chan struct{}
+}
+
+// pendingCall a request waiting for its response
+type pendingCall struct {
+	response      chan *message
+	progressToken string
+	onProgress    func(*protocol.ProgressNotification)
+}
+
+// message a JSON-RPC message of any kind
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  protocol.Method `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// DialOption configures a connection before it is initialized
+type DialOption func(*Conn)
+
+// WithNotificationHandler sets the handler of the server notifications other than progress
+func WithNotificationHandler(handler func(method protocol.Method, params json.RawMessage)) DialOption {
+	return func(c *Conn) {
+		c.OnNotification = handler
+	}
+}
+
+// Dial connects to the server and initializes the session as client info.
+// The logger is also used by the transport, it must not write to stdout when stdout carries MCP messages.
+func Dial(ctx context.Context, cfg Config, logger pkg.Logger, info protocol.Implementation, opts ...DialOption) (*Conn, error) {
+	c := &Conn{
+		logger:    logger,
+		pending:   make(map[string]*pendingCall),
+		cancelled: make(map[string]bool),
+		done:      make(chan struct{}),
+	}
+	t, err := NewTransport(cfg, &closingLogger{Logger: logger, conn: c})
+	if err != nil {
+		return nil, err
+	}
+	c.transport = t
+	for _, opt := range opts {
+		opt(c)
+	}
+	t.SetReceiver(transport.NewClientReceiver(c.receive, c.interrupt))
+	if err = t.Start(); err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", cfg.endpoint(), err)
+	}
+
+	var result protocol.InitializeResult
+	if err = c.Call(ctx, protocol.Initialize, protocol.NewInitializeRequest(&info, &protocol.ClientCapabilities{}), &result); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to initialize session with %s: %v", cfg.endpoint(), err)
+	}
+	if result.ServerInfo != nil {
+		c.ServerInfo = *result.ServerInfo
+	}
+	if result.Capabilities != nil {
+		c.Capabilities = *result.Capabilities
+	}
+	c.ProtocolVersion = result.ProtocolVersion
+	c.Instructions = result.Instructions
+
+	if err = c.Notify(ctx, protocol.NotificationInitialized, protocol.NewInitializedNotification()); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to initialize session with %s: %v", cfg.endpoint(), err)
+	}
+	return c, nil
+}
+
+// Call sends a request and decodes its result into result, a *json.RawMessage keeps it as sent.
+// A request whose context ends is cancelled on the server.
+func (c *Conn) Call(ctx context.Context, method protocol.Method, params, result interface{}) error {
+	return c.CallWithProgress(ctx, method, params, nil, result)
+}
+
+// CallWithProgress is Call with a progress token, the progress notifications of the request are passed to onProgress
+func (c *Conn) CallWithProgress(ctx context.Context, method protocol.Method, params interface{},
+	onProgress func(*protocol.ProgressNotification), result interface{}) error {
+	id := strconv.FormatInt(c.nextID.Add(1), 10)
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s params: %v", method, err)
+	}
+
+	pending := &pendingCall{response: make(chan *message, 1), onProgress: onProgress}
+	if onProgress != nil {
+		pending.progressToken = "progress-" + id
+		if rawParams, err = withMeta(rawParams, protocol.ProgressTokenKey, pending.progressToken); err != nil {
+			return fmt.Errorf("failed to encode %s params: %v", method, err)
+		}
+	}
+
+	c.mu.Lock()
+	if c.closeErr != nil {
+		c.mu.Unlock()
+		return c.closeErr
+	}
+	c.pending[id] = pending
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err = c.send(ctx, &message{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method, Params: rawParams}); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-pending.response:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil {
+			return nil
+		}
+		if err = json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %v", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		// Answers from now on arrive after the cancellation
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.cancelled[id] = true
+		c.mu.Unlock()
+		cancelled := protocol.NewCancelledNotification(json.RawMessage(id), ctx.Err().Error())
+		if err := c.Notify(context.Background(), protocol.NotificationCancelled, cancelled); err != nil {
+			c.logger.Warnf("Failed to send cancellation notification: %v", err)
+		}
+		return ctx.Err()
+	case <-c.done:
+		return c.err()
+	}
+}
+
+// Notify sends a notification
+func (c *Conn) Notify(ctx context.Context, method protocol.Method, params interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s params: %v", method, err)
+	}
+	return c.send(ctx, &message{JSONRPC: "2.0", Method: method, Params: rawParams})
+}
+
+// Close ends the session and the transport
+func (c *Conn) Close() error {
+	if !c.shutdown(ErrClosed) {
+		return nil
+	}
+	return c.transport.Close()
+}
+
+// AnswersAfterCancel returns the number of answers the server sent to requests the client cancelled,
+// which the specification says it should not send. They are dropped.
+func (c *Conn) AnswersAfterCancel() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lateAnswers
+}
+
+// Done is closed when the connection is closed or dropped
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Conn) send(ctx context.Context, msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err = c.transport.Send(ctx, data); err != nil {
+		return fmt.Errorf("failed to send %s: %v", msg.Method, err)
+	}
+	return nil
+}
+
+// receive dispatches a message of the server
+func (c *Conn) receive(_ context.Context, data []byte) error {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("invalid message: %v", err)
+	}
+
+	switch {
+	case len(msg.ID) > 0 && msg.Method == "":
+		c.mu.Lock()
+		pending, ok := c.pending[string(msg.ID)]
+		if !ok && c.cancelled[string(msg.ID)] {
+			delete(c.cancelled, string(msg.ID))
+			c.lateAnswers++
+		}
+		c.mu.Unlock()
+		if ok {
+			pending.response <- &msg
+		}
+	case len(msg.ID) > 0:
+		// Requests of the server are answered asynchronously, the transport waits for receive to return
+		go c.answer(&msg)
+	case msg.Method == protocol.NotificationProgress:
+		var notify protocol.ProgressNotification
+		if err := json.Unmarshal(msg.Params, &notify); err != nil {
+			return fmt.Errorf("invalid progress notification: %v", err)
+		}
+		token := fmt.Sprint(notify.ProgressToken)
+		c.mu.Lock()
+		var onProgress func(*protocol.ProgressNotification)
+		for _, pending := range c.pending {
+			if pending.progressToken != "" && pending.progressToken == token {
+				onProgress = pending.onProgress
+				break
+			}
+		}
+		c.mu.Unlock()
+		if onProgress != nil {
+			onProgress(&notify)
+		}
+	default:
+		if c.OnNotification != nil {
+			c.OnNotification(msg.Method, msg.Params)
+		}
+	}
+	return nil
+}
+
+// answer responds to a request of the server, only ping is supported
+func (c *Conn) answer(req *message) {
+	resp := &message{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == protocol.Ping {
+		resp.Result = json.RawMessage("{}")
+	} else {
+		resp.Error = &RPCError{Code: protocol.MethodNotFound, Message: fmt.Sprintf("method %s is not supported by the client", req.Method)}
+	}
+	if err := c.send(context.Background(), resp); err != nil {
+		c.logger.Warnf("Failed to answer %s request: %v", req.Method, err)
+	}
+}
+
+func (c *Conn) interrupt(err error) {
+	c.shutdown(fmt.Errorf("%w: %v", ErrClosed, err))
+}
+
+// shutdown marks the connection closed, false when it already was
+func (c *Conn) shutdown(err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closeErr != nil {
+		return false
+	}
+	c.closeErr = err
+	close(c.done)
+	return true
+}
+
+func (c *Conn) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeErr
+}
+
+// withMeta sets a _meta entry of the params object
+func withMeta(params json.RawMessage, key string, value interface{}) (json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if len(params) > 0 && string(params) != "null" {
+		if err := json.Unmarshal(params, &fields); err != nil {
+			return nil, err
+		}
+	}
+	meta := make(map[string]interface{})
+	if raw, ok := fields["_meta"]; ok {
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return nil, err
+		}
+	}
+	meta[key] = value
+
+	rawMeta, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	fields["_meta"] = rawMeta
+	return json.Marshal(fields)
+}
+
+// closingLogger is the logger of the transport. The stdio transport closes its pipes while still reading them,
+// the errors it reports once the connection is closing are expected and only logged at debug level.
+type closingLogger struct {
+	pkg.Logger
+	conn *Conn
+}
+
+func (l *closingLogger) Warnf(format string, a ...any) {
+	if l.conn.err() != nil {
+		l.Debugf(format, a...)
+		return
+	}
+	l.Logger.Warnf(format, a...)
+}
+
+func (l *closingLogger) Errorf(format string, a ...any) {
+	if l.conn.err() != nil {
+		l.Debugf(format, a...)
+		return
+	}
+	l.Logger.Errorf(format, a...)
+}
@@ -0,0 +1,102 @@
+package mcpclient
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+// DecodeData decodes the base64 data of an image or audio item
+func (c Content) DecodeData() ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(c.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 %s data: %v", c.Type, err)
+	}
+	return data, nil
+}
+
+// DecodeBlob decodes the base64 contents of a binary resource
+func (r ResourceContents) DecodeBlob() ([]byte, error) {
+	blob, err := base64.StdEncoding.DecodeString(r.Blob)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 blob of %s: %v", r.URI, err)
+	}
+	return blob, nil
+}
+
+// ToProtocol converts the tool for registration on an SDK server, the input schema is passed through as is
+func (t *Tool) ToProtocol() *protocol.Tool {
+	tool := protocol.NewToolWithRawSchema(t.Name, t.Description, t.InputSchema)
+	tool.Annotations = t.Annotations
+	return tool
+}
+
+// ToProtocol converts the result for an SDK server.
+// The SDK result has no structured content, it is dropped.
+func (r *ToolResult) ToProtocol() (*protocol.CallToolResult, error) {
+	result := &protocol.CallToolResult{IsError: r.IsError, Content: make([]protocol.Content, 0, len(r.Content))}
+	for _, item := range r.Content {
+		content, err := item.ToProtocol()
+		if err != nil {
+			return nil, err
+		}
+		result.Content = append(result.Content, content)
+	}
+	return result, nil
+}
+
+// ToProtocol converts the prompt for an SDK server
+func (r *PromptResult) ToProtocol() (*protocol.GetPromptResult, error) {
+	result := &protocol.GetPromptResult{Description: r.Description, Messages: make([]*protocol.PromptMessage, 0, len(r.Messages))}
+	for _, message := range r.Messages {
+		content, err := message.Content.ToProtocol()
+		if err != nil {
+			return nil, err
+		}
+		result.Messages = append(result.Messages, &protocol.PromptMessage{Role: message.Role, Content: content})
+	}
+	return result, nil
+}
+
+// ToProtocol converts the content item to its SDK type
+func (c Content) ToProtocol() (protocol.Content, error) {
+	switch c.Type {
+	case "text":
+		return &protocol.TextContent{Type: c.Type, Text: c.Text}, nil
+	case "image", "audio":
+		data, err := c.DecodeData()
+		if err != nil {
+			return nil, err
+		}
+		if c.Type == "audio" {
+			return &protocol.AudioContent{Type: c.Type, Data: data, MimeType: c.MimeType}, nil
+		}
+		return &protocol.ImageContent{Type: c.Type, Data: data, MimeType: c.MimeType}, nil
+	case "resource_link":
+		return &protocol.ResourceLink{Type: c.Type, URI: c.URI, Name: c.Name, Description: c.Description, MIMEType: c.MimeType}, nil
+	case "resource":
+		if c.Resource == nil {
+			return nil, fmt.Errorf("embedded resource without contents")
+		}
+		resource, err := c.Resource.ToProtocol()
+		if err != nil {
+			return nil, err
+		}
+		return protocol.NewEmbeddedResource(resource, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", c.Type)
+	}
+}
+
+// ToProtocol converts the resource contents to their SDK type
+func (r ResourceContents) ToProtocol() (protocol.ResourceContents, error) {
+	if r.Blob == "" {
+		return &protocol.TextResourceContents{URI: r.URI, Text: r.Text, MimeType: r.MimeType}, nil
+	}
+	blob, err := r.DecodeBlob()
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.BlobResourceContents{URI: r.URI, Blob: blob, MimeType: r.MimeType}, nil
+}
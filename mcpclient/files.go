@@ -0,0 +1,183 @@
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// extensions of the MIME types MCP servers commonly return, the system table varies between machines
+var extensions = map[string]string{
+	"image/png":        ".png",
+	"image/jpeg":       ".jpg",
+	"image/jpg":        ".jpg",
+	"image/gif":        ".gif",
+	"image/webp":       ".webp",
+	"image/svg+xml":    ".svg",
+	"image/bmp":        ".bmp",
+	"audio/mpeg":       ".mp3",
+	"audio/mp3":        ".mp3",
+	"audio/wav":        ".wav",
+	"audio/x-wav":      ".wav",
+	"audio/wave":       ".wav",
+	"audio/ogg":        ".ogg",
+	"audio/flac":       ".flac",
+	"audio/aac":        ".aac",
+	"audio/mp4":        ".m4a",
+	"audio/pcm":        ".pcm",
+	"video/mp4":        ".mp4",
+	"video/webm":       ".webm",
+	"application/pdf":  ".pdf",
+	"application/json": ".json",
+	"application/zip":  ".zip",
+	"text/plain":       ".txt",
+	"text/markdown":    ".md",
+	"text/html":        ".html",
+	"text/csv":         ".csv",
+}
+
+// Extension returns the file extension of a MIME type, ".bin" when it is unknown
+func Extension(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(mimeType))
+	}
+	if ext, ok := extensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// File the decoded data of a content item
+type File struct {
+	URI      string
+	MimeType string
+	Data     []byte
+}
+
+// Ext returns the extension of the file: the one of its MIME type, else the one of its URI
+func (f *File) Ext() string {
+	if f.MimeType != "" {
+		return Extension(f.MimeType)
+	}
+	if u, err := url.Parse(f.URI); err == nil {
+		if ext := path.Ext(u.Path); ext != "" {
+			return ext
+		}
+	}
+	return ".bin"
+}
+
+// SavedFile a file written by Save
+type SavedFile struct {
+	// Item is the position of the content item in the result, from 1
+	Item     int    `json:"item"`
+	Type     string `json:"type"`
+	URI      string `json:"uri,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Path     string `json:"path"`
+	Size     int    `json:"size"`
+}
+
+// Files returns the files of a content item: the data of an image or audio item, the contents of an
+// embedded resource, or the contents of a linked resource read from the server. Text items have none.
+func (c *Conn) Files(ctx context.Context, item Content) ([]File, error) {
+	switch item.Type {
+	case "image", "audio":
+		data, err := item.DecodeData()
+		if err != nil {
+			return nil, err
+		}
+		return []File{{MimeType: item.MimeType, Data: data}}, nil
+	case "resource":
+		if item.Resource == nil {
+			return nil, nil
+		}
+		file, err := item.Resource.file()
+		if err != nil {
+			return nil, err
+		}
+		return []File{file}, nil
+	case "resource_link":
+		contents, err := c.ReadResource(ctx, item.URI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read linked resource %s: %v", item.URI, err)
+		}
+		files := make([]File, 0, len(contents))
+		for _, content := range contents {
+			if content.MimeType == "" {
+				content.MimeType = item.MimeType
+			}
+			file, err := content.file()
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+		return files, nil
+	}
+	return nil, nil
+}
+
+func (r *ResourceContents) file() (File, error) {
+	if r.Blob != "" {
+		data, err := r.DecodeBlob()
+		if err != nil {
+			return File{}, err
+		}
+		return File{URI: r.URI, MimeType: r.MimeType, Data: data}, nil
+	}
+	file := File{URI: r.URI, MimeType: r.MimeType, Data: []byte(r.Text)}
+	if file.MimeType == "" && path.Ext(r.URI) == "" {
+		file.MimeType = "text/plain"
+	}
+	return file, nil
+}
+
+// Save writes the image, audio and resource items of a result to dir as prefix_<item><ext>, linked resources
+// are read from the server. A resource with several contents gives prefix_<item>_<n><ext> files.
+// Text items are skipped. An item that fails does not stop the others, the errors are joined.
+func (c *Conn) Save(ctx context.Context, items []Content, dir, prefix string) ([]SavedFile, error) {
+	var (
+		saved []SavedFile
+		errs  []error
+	)
+	for i, item := range items {
+		files, err := c.Files(ctx, item)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("item %d: %v", i+1, err))
+			continue
+		}
+		for n, file := range files {
+			name := fmt.Sprintf("%s_%d", prefix, i+1)
+			if len(files) > 1 {
+				name += fmt.Sprintf("_%d", n+1)
+			}
+			target := filepath.Join(dir, name+file.Ext())
+			if err = WriteFile(target, file.Data); err != nil {
+				errs = append(errs, fmt.Errorf("item %d: %v", i+1, err))
+				continue
+			}
+			saved = append(saved, SavedFile{Item: i + 1, Type: item.Type, URI: file.URI, MimeType: file.MimeType,
+				Path: target, Size: len(file.Data)})
+		}
+	}
+	return saved, errors.Join(errs...)
+}
+
+// WriteFile writes data to path, creating its directory
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,154 @@
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+)
+
+// Transports an MCP server can be reached over
+const (
+	Stdio      = "stdio"
+	SSE        = "sse"
+	Streamable = "streamable"
+)
+
+// Config how to reach an MCP server
+type Config struct {
+	// Transport is Stdio, SSE or Streamable
+	Transport string
+	// URL is the SSE endpoint (e.g. http://host/sse) or the streamable endpoint (e.g. http://host/mcp)
+	URL string
+	// Command and Args start a stdio server, Env adds KEY=VALUE entries to its environment
+	Command string
+	Args    []string
+	Env     []string
+	// Header is sent with every HTTP request, e.g. Authorization
+	Header http.Header
+	// Timeout bounds each request, 0 keeps the SDK default
+	Timeout time.Duration
+	// StreamError is called when a response stream of a streamable server breaks off, e.g. because the server
+	// went away. The SDK only logs it, so without StreamError a dropped server goes unnoticed until the next send.
+	StreamError func(err error)
+}
+
+// Validate checks that the configuration names a complete endpoint
+func (c Config) Validate() error {
+	switch c.Transport {
+	case Stdio:
+		if c.Command == "" {
+			return fmt.Errorf("stdio transport needs a command")
+		}
+	case SSE, Streamable:
+		if c.URL == "" {
+			return fmt.Errorf("%s transport needs a URL", c.Transport)
+		}
+	default:
+		return fmt.Errorf("unknown transport %q, expected stdio, sse or streamable", c.Transport)
+	}
+	return nil
+}
+
+// NewTransport creates the client transport described by the configuration
+func NewTransport(cfg Config, logger pkg.Logger) (transport.ClientTransport, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	roundTripper := &headerTransport{header: cfg.Header, next: http.DefaultTransport}
+	httpClient := &http.Client{Transport: roundTripper}
+	switch cfg.Transport {
+	case Stdio:
+		return transport.NewStdioClientTransport(cfg.Command, cfg.Args,
+			transport.WithStdioClientOptionEnv(cfg.Env...),
+			transport.WithStdioClientOptionLogger(logger))
+	case SSE:
+		opts := []transport.SSEClientTransportOption{
+			transport.WithSSEClientOptionHTTPClient(httpClient),
+			transport.WithSSEClientOptionLogger(logger),
+		}
+		if cfg.Timeout > 0 {
+			opts = append(opts, transport.WithSSEClientOptionReceiveTimeout(cfg.Timeout))
+		}
+		return transport.NewSSEClientTransport(cfg.URL, opts...)
+	default:
+		// The SSE transport reports a lost connection by itself
+		roundTripper.streamError = cfg.StreamError
+		opts := []transport.StreamableHTTPClientTransportOption{
+			transport.WithStreamableHTTPClientOptionHTTPClient(httpClient),
+			transport.WithStreamableHTTPClientOptionLogger(logger),
+		}
+		if cfg.Timeout > 0 {
+			opts = append(opts, transport.WithStreamableHTTPClientOptionReceiveTimeout(cfg.Timeout))
+		}
+		return transport.NewStreamableHTTPClientTransport(cfg.URL, opts...)
+	}
+}
+
+// ParseHeaders converts "Key: Value" entries into a header
+func ParseHeaders(entries []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected \"Key: Value\"", entry)
+		}
+		header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+func (c Config) endpoint() string {
+	if c.Transport == Stdio {
+		return strings.TrimSpace(c.Command + " " + strings.Join(c.Args, " "))
+	}
+	return c.URL
+}
+
+// headerTransport adds fixed headers to every request and reports broken event streams to streamError when set
+type headerTransport struct {
+	header      http.Header
+	streamError func(err error)
+	next        http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.header) > 0 {
+		req = req.Clone(req.Context())
+		for key, values := range t.header {
+			req.Header[key] = values
+		}
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && t.streamError != nil && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		resp.Body = &streamBody{ReadCloser: resp.Body, ctx: req.Context(), onError: t.streamError}
+	}
+	return resp, err
+}
+
+// streamBody an event stream that reports the first read error other than its end or its cancellation
+type streamBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	onError  func(err error)
+	reported sync.Once
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) && b.ctx.Err() == nil {
+		b.reported.Do(func() { b.onError(err) })
+	}
+	return n, err
+}
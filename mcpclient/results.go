@@ -0,0 +1,195 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+// maxPages bounds the pages fetched for one list, a server repeating cursors would never end it
+const maxPages = 1000
+
+// Tool a tool as listed by the server, the input schema is kept as sent
+type Tool struct {
+	Name        string                    `json:"name"`
+	Description string                    `json:"description,omitempty"`
+	InputSchema json.RawMessage           `json:"inputSchema"`
+	Annotations *protocol.ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// Content a content item of a tool result or prompt message, whatever its type.
+// Data and Blob stay base64 encoded as sent.
+type Content struct {
+	Type string `json:"type"`
+	// Text of a text item
+	Text string `json:"text,omitempty"`
+	// Data and MimeType of an image or audio item
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	// URI, Name and Description of a resource link
+	URI         string `json:"uri,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Resource of an embedded resource
+	Resource *ResourceContents `json:"resource,omitempty"`
+}
+
+// ResourceContents the contents of a resource, Text for text resources and base64 encoded Blob for binary ones
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// ToolResult the result of a tool call
+type ToolResult struct {
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// PromptMessage a message of a prompt
+type PromptMessage struct {
+	Role    protocol.Role `json:"role"`
+	Content Content       `json:"content"`
+}
+
+// PromptResult the messages of a prompt
+type PromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// ListTools lists every tool of the server
+func (c *Conn) ListTools(ctx context.Context) ([]*Tool, error) {
+	var tools []*Tool
+	err := c.list(ctx, protocol.ToolsList, func(page json.RawMessage) (string, error) {
+		var result struct {
+			Tools      []*Tool `json:"tools"`
+			NextCursor string  `json:"nextCursor"`
+		}
+		err := json.Unmarshal(page, &result)
+		tools = append(tools, result.Tools...)
+		return result.NextCursor, err
+	})
+	return tools, err
+}
+
+// ListPrompts lists every prompt of the server
+func (c *Conn) ListPrompts(ctx context.Context) ([]*protocol.Prompt, error) {
+	var prompts []*protocol.Prompt
+	err := c.list(ctx, protocol.PromptsList, func(page json.RawMessage) (string, error) {
+		var result struct {
+			Prompts    []*protocol.Prompt `json:"prompts"`
+			NextCursor string             `json:"nextCursor"`
+		}
+		err := json.Unmarshal(page, &result)
+		prompts = append(prompts, result.Prompts...)
+		return result.NextCursor, err
+	})
+	return prompts, err
+}
+
+// ListResources lists every resource of the server
+func (c *Conn) ListResources(ctx context.Context) ([]*protocol.Resource, error) {
+	var resources []*protocol.Resource
+	err := c.list(ctx, protocol.ResourcesList, func(page json.RawMessage) (string, error) {
+		var result struct {
+			Resources  []*protocol.Resource `json:"resources"`
+			NextCursor string               `json:"nextCursor"`
+		}
+		err := json.Unmarshal(page, &result)
+		resources = append(resources, result.Resources...)
+		return result.NextCursor, err
+	})
+	return resources, err
+}
+
+// ListResourceTemplates lists every resource template of the server
+func (c *Conn) ListResourceTemplates(ctx context.Context) ([]*protocol.ResourceTemplate, error) {
+	var templates []*protocol.ResourceTemplate
+	err := c.list(ctx, protocol.ResourceListTemplates, func(page json.RawMessage) (string, error) {
+		var result struct {
+			ResourceTemplates []*protocol.ResourceTemplate `json:"resourceTemplates"`
+			NextCursor        string                       `json:"nextCursor"`
+		}
+		err := json.Unmarshal(page, &result)
+		templates = append(templates, result.ResourceTemplates...)
+		return result.NextCursor, err
+	})
+	return templates, err
+}
+
+// ReadResource reads the contents of a resource
+func (c *Conn) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	var result struct {
+		Contents []ResourceContents `json:"contents"`
+	}
+	if err := c.Call(ctx, protocol.ResourcesRead, map[string]string{"uri": uri}, &result); err != nil {
+		return nil, err
+	}
+	return result.Contents, nil
+}
+
+// GetPrompt renders a prompt with its arguments
+func (c *Conn) GetPrompt(ctx context.Context, name string, args map[string]string) (*PromptResult, error) {
+	var result PromptResult
+	if err := c.Call(ctx, protocol.PromptsGet, &protocol.GetPromptRequest{Name: name, Arguments: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CallTool calls a tool with its JSON arguments, nil sends an empty object as the SDK server requires arguments.
+// When onProgress is set the call carries a progress token and its progress notifications are passed to it.
+// A tool failure is a result with IsError set, not an error.
+func (c *Conn) CallTool(ctx context.Context, name string, args json.RawMessage,
+	onProgress func(*protocol.ProgressNotification)) (*ToolResult, error) {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	params := struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}{Name: name, Arguments: args}
+
+	var result ToolResult
+	if err := c.CallWithProgress(ctx, protocol.ToolsCall, params, onProgress, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// list requests every page of a list, handle decodes a page and returns its next cursor
+func (c *Conn) list(ctx context.Context, method protocol.Method, handle func(page json.RawMessage) (string, error)) error {
+	seen := make(map[string]bool)
+	cursor := ""
+	for page := 0; ; page++ {
+		if page == maxPages {
+			return fmt.Errorf("%s returned more than %d pages", method, maxPages)
+		}
+
+		params := struct {
+			Cursor string `json:"cursor,omitempty"`
+		}{Cursor: cursor}
+		var result json.RawMessage
+		if err := c.Call(ctx, method, params, &result); err != nil {
+			return err
+		}
+		next, err := handle(result)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s result: %v", method, err)
+		}
+		if next == "" {
+			return nil
+		}
+		if seen[next] {
+			return fmt.Errorf("%s returned cursor %q twice", method, next)
+		}
+		seen[next] = true
+		cursor = next
+	}
+}
@@ -1 +0,0 @@
-go build -v -o miniMaxMCPClient.exe main.go
\ No newline at end of file
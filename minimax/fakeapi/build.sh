@@ -0,0 +1 @@
+go build -v -o fakeMiniMaxAPI .
\ No newline at end of file
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mcp/logging"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const usage = `Usage: fakeMiniMaxAPI [flags]
+
+Answers the MiniMax API calls of the MiniMax MCP server with canned successful responses, so that
+the server can be run and checked without an API key or cost:
+
+  fakeMiniMaxAPI -addr 127.0.0.1:18777 -delay 2s
+
+and APIHost = "http://127.0.0.1:18777" in the server configuration. GET /_fake/stats returns the
+number of API requests received and of those the server aborted during the delay. Chat
+completions that set stream are answered with server-sent events.
+
+Flags:
+`
+
+// Canned media, only their first bytes look like the real formats
+var (
+	fakeAudio = []byte("ID3\x03\x00\x00\x00\x00\x00\x00fake audio")
+	fakeImage = []byte("\x89PNG\r\n\x1a\nfake image")
+	fakeVideo = []byte("\x00\x00\x00\x18ftypmp42fake video")
+)
+
+// The chat completion answer, streamed in fakeAnswerChunks when the request sets stream
+var (
+	fakeAnswerChunks = []string{"fake ", "answer"}
+	fakeAnswer       = strings.Join(fakeAnswerChunks, "")
+	fakeUsage        = map[string]int{"prompt_tokens": 1, "completion_tokens": 2, "total_tokens": 3}
+)
+
+// fakeFileID the file every video task and upload refers to
+const fakeFileID = "1000"
+
+// statsPath the path of the request counters, outside the MiniMax API
+const statsPath = "/_fake/stats"
+
+type fakeAPI struct {
+	addr  string
+	delay time.Duration
+
+	requests atomic.Int64
+	aborted  atomic.Int64
+}
+
+func main() {
+	flags := flag.NewFlagSet("fakeMiniMaxAPI", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	addr := flags.String("addr", "127.0.0.1:18777", "listen address")
+	delay := flags.Duration("delay", 0, "delay of every response, long enough to cancel the calls of the server")
+	logLevel := flags.String("log-level", "info", "debug, info, warn or error, logs go to stderr")
+	_ = flags.Parse(os.Args[1:])
+
+	logger, _, err := logging.New(logging.Config{Level: *logLevel, Stdio: true})
+	if err != nil {
+		slog.Error("Failed to set up logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	api := &fakeAPI{addr: *addr, delay: *delay}
+	slog.Info("Fake MiniMax API listening", "addr", *addr, "delay", *delay)
+	if err = http.ListenAndServe(*addr, api); err != nil {
+		slog.Error("Fake MiniMax API stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == statsPath {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int64{"requests": f.requests.Load(), "aborted": f.aborted.Load()})
+		return
+	}
+
+	slog.Info("Request", "method", r.Method, "path", r.URL.Path)
+	f.requests.Add(1)
+	// The server only notices that the client went away once the body has been read
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-r.Context().Done():
+			f.aborted.Add(1)
+			slog.Info("Request cancelled by the client", "path", r.URL.Path)
+			return
+		}
+	}
+
+	if name, ok := strings.CutPrefix(r.URL.Path, "/download/"); ok {
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		w.Write(fakeVideo)
+		return
+	}
+
+	var response map[string]interface{}
+	switch r.URL.Path {
+	case "/v1/t2a_v2", "/v1/music_generation":
+		response = map[string]interface{}{"data": map[string]interface{}{"audio": hex.EncodeToString(fakeAudio)}}
+	case "/v1/image_generation":
+		response = map[string]interface{}{"data": map[string]interface{}{
+			"image_base64": []string{base64.StdEncoding.EncodeToString(fakeImage)},
+			"image_urls":   []string{fmt.Sprintf("http://%s/download/image.png", f.addr)},
+		}}
+	case "/v1/get_voice":
+		response = map[string]interface{}{
+			"system_voice":  []map[string]string{{"voice_id": "male-qn-qingse", "voice_name": "青涩青年音色"}},
+			"voice_cloning": []map[string]string{},
+		}
+	case "/v1/voice_clone":
+		response = map[string]interface{}{}
+	case "/v1/video_generation":
+		response = map[string]interface{}{"task_id": "fake-task"}
+	case "/v1/query/video_generation":
+		response = map[string]interface{}{"task_id": r.URL.Query().Get("task_id"), "status": "Success", "file_id": fakeFileID}
+	case "/v1/files/retrieve", "/v1/files/upload", "/v1/music_upload":
+		response = map[string]interface{}{"file": map[string]interface{}{
+			"file_id":      1000,
+			"filename":     "video.mp4",
+			"purpose":      "video_generation",
+			"bytes":        len(fakeVideo),
+			"created_at":   time.Now().Unix(),
+			"download_url": fmt.Sprintf("http://%s/download/video.mp4", f.addr),
+		}}
+	case "/v1/files/list":
+		response = map[string]interface{}{"files": []interface{}{}}
+	case "/v1/text/chatcompletion_v2":
+		var request struct {
+			Stream bool `json:"stream"`
+		}
+		json.Unmarshal(body, &request)
+		if request.Stream {
+			f.streamChat(w)
+			return
+		}
+		response = map[string]interface{}{
+			"id":      "fake-completion",
+			"model":   "MiniMax-Text-01",
+			"choices": []map[string]interface{}{{"index": 0, "finish_reason": "stop", "message": map[string]string{"role": "assistant", "content": fakeAnswer}}},
+			"usage":   fakeUsage,
+		}
+	case "/v1/embeddings":
+		var request struct {
+			Texts []string `json:"texts"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		vectors := make([][]float64, 0, len(request.Texts))
+		for i := range request.Texts {
+			vectors = append(vectors, []float64{1, float64(i), 0.5})
+		}
+		response = map[string]interface{}{"vectors": vectors}
+	default:
+		// Unknown endpoints succeed without data, the server reports what it misses
+		response = map[string]interface{}{}
+	}
+	response["base_resp"] = map[string]interface{}{"status_code": 0, "status_msg": "success"}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Trace-Id", "fake-trace")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Warn("Failed to write response", "path", r.URL.Path, "error", err)
+	}
+}
+
+// streamChat answers a streaming chat completion with server-sent events, one per chunk of the answer.
+// Like the MiniMax API the last event repeats the full message with the finish reason and usage.
+func (f *fakeAPI) streamChat(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Trace-Id", "fake-trace")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(chunk map[string]interface{}) bool {
+		chunk["id"], chunk["model"] = "fake-completion", "MiniMax-Text-01"
+		data, err := json.Marshal(chunk)
+		if err == nil {
+			_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		if err != nil {
+			slog.Warn("Failed to write stream chunk", "error", err)
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	for _, delta := range fakeAnswerChunks {
+		if !send(map[string]interface{}{"choices": []map[string]interface{}{{"index": 0, "delta": map[string]string{"role": "assistant", "content": delta}}}}) {
+			return
+		}
+	}
+	if !send(map[string]interface{}{
+		"choices":   []map[string]interface{}{{"index": 0, "finish_reason": "stop", "message": map[string]string{"role": "assistant", "content": fakeAnswer}}},
+		"usage":     fakeUsage,
+		"base_resp": map[string]interface{}{"status_code": 0, "status_msg": "success"},
+	}) {
+		return
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
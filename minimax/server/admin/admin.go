@@ -0,0 +1,223 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mcp/minimax/server/minimax"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// reachabilityTTL how long a MiniMax reachability result is reused, readiness probes are frequent
+const reachabilityTTL = 30 * time.Second
+
+// secretKeyPattern configuration keys whose values are masked on the admin endpoint
+var secretKeyPattern = regexp.MustCompile(`(?i)key|token|secret|password`)
+
+// Server health, readiness and admin HTTP endpoints
+type Server struct {
+	Client  *minimax.APIClient
+	Tracker *minimax.Tracker
+	// Config is shown on the admin endpoint with secrets masked
+	Config *ini.File
+	// OutputPath must be writable for the server to be ready
+	OutputPath string
+	// Token protects the admin endpoint with a bearer token when set
+	Token string
+	Mode  string
+	// Drain reports the server not ready while it shuts down
+	Drain *minimax.Drain
+
+	startedAt time.Time
+
+	mu          sync.Mutex
+	checkedAt   time.Time
+	reachErr    error
+	checkRunner sync.Mutex
+}
+
+// Register mounts /healthz and /readyz on the MCP mux, and /admin only when a token protects it:
+// the session IDs it lists are accepted by the MCP endpoints.
+func (s *Server) Register(mux *http.ServeMux) {
+	s.register(mux, s.Token != "")
+}
+
+// RegisterAdminAddr mounts every endpoint on the mux of the separate admin listener at addr.
+// Without a token it refuses to serve /admin on an address reachable from other hosts.
+func (s *Server) RegisterAdminAddr(mux *http.ServeMux, addr string) error {
+	if s.Token == "" && !isLoopback(addr) {
+		return fmt.Errorf("admin address %s is not a loopback address, set a token to serve /admin on it", addr)
+	}
+	s.register(mux, true)
+	return nil
+}
+
+func (s *Server) register(mux *http.ServeMux, withAdmin bool) {
+	if s.startedAt.IsZero() {
+		s.startedAt = time.Now()
+	}
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	if withAdmin {
+		mux.HandleFunc("/admin", s.handleAdmin)
+	}
+}
+
+// isLoopback reports whether the listen address addr only accepts local connections
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// handleHealth reports that the process is up and serving HTTP
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady checks the configuration, the MiniMax API and the output directory
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{
+		"config":  checkResult(s.checkConfig()),
+		"minimax": checkResult(s.checkMiniMax(r.Context())),
+		"storage": checkResult(s.checkStorage()),
+	}
+	if s.Drain != nil && s.Drain.Draining() {
+		checks["shutdown"] = "draining"
+	}
+
+	status, code := "ok", http.StatusOK
+	for _, result := range checks {
+		if result != "ok" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, code, map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+func (s *Server) checkConfig() error {
+	if s.Client == nil || s.Client.APIKey == "" || s.Client.APIHost == "" {
+		return fmt.Errorf("MiniMax APIKey and APIHost must be set")
+	}
+	return nil
+}
+
+// checkMiniMax lists voice clone files, a cheap authenticated call, and caches the result
+func (s *Server) checkMiniMax(ctx context.Context) error {
+	// Concurrent probes wait for a single request instead of all calling the API
+	s.checkRunner.Lock()
+	defer s.checkRunner.Unlock()
+
+	s.mu.Lock()
+	if time.Since(s.checkedAt) < reachabilityTTL {
+		err := s.reachErr
+		s.mu.Unlock()
+		return err
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, err := s.Client.ListFiles(ctx, minimax.FilePurposeVoiceClone)
+	if err != nil {
+		slog.WarnContext(ctx, "MiniMax readiness check failed", "error", err)
+	}
+
+	s.mu.Lock()
+	s.checkedAt, s.reachErr = time.Now(), err
+	s.mu.Unlock()
+	return err
+}
+
+func (s *Server) checkStorage() error {
+	if err := os.MkdirAll(s.OutputPath, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	file, err := os.CreateTemp(s.OutputPath, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("output directory is not writable: %v", err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// handleAdmin lists active sessions, running jobs and the configuration with secrets masked
+func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" {
+		expected := "Bearer " + s.Token
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+	}
+
+	var (
+		sessions = []minimax.Session{}
+		jobs     = []minimax.Job{}
+	)
+	if s.Tracker != nil {
+		sessions, jobs = s.Tracker.Sessions(), s.Tracker.Jobs()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"mode":       s.Mode,
+		"started_at": s.startedAt,
+		"uptime":     time.Since(s.startedAt).Round(time.Second).String(),
+		"sessions":   sessions,
+		"jobs":       jobs,
+		"config":     maskedConfig(s.Config),
+	})
+}
+
+// maskedConfig returns every configured section and key, masking secret values
+func maskedConfig(cfg *ini.File) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	if cfg == nil {
+		return result
+	}
+	for _, section := range cfg.Sections() {
+		if len(section.Keys()) == 0 {
+			continue
+		}
+		values := make(map[string]string)
+		for _, key := range section.Keys() {
+			value := key.String()
+			if value != "" && secretKeyPattern.MatchString(key.Name()) {
+				value = "[REDACTED]"
+			}
+			values[key.Name()] = value
+		}
+		result[section.Name()] = values
+	}
+	return result
+}
+
+func checkResult(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
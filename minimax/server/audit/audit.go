@@ -0,0 +1,250 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"mcp/logging"
+	"mcp/minimax/server/quota"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Call outcomes
+const (
+	OutcomeOK     = "ok"
+	OutcomeError  = "error"
+	OutcomeFailed = "failed"
+)
+
+// Record one audited tool call, written as a JSON line
+type Record struct {
+	Time      time.Time              `json:"time"`
+	RequestID string                 `json:"request_id,omitempty"`
+	SessionID string                 `json:"session_id,omitempty"`
+	Client    string                 `json:"client,omitempty"`
+	Tool      string                 `json:"tool"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+	// TraceIDs are the MiniMax Trace-Id headers of the API requests made by the call
+	TraceIDs      []string    `json:"minimax_trace_ids,omitempty"`
+	TaskID        string      `json:"task_id,omitempty"`
+	Outcome       string      `json:"outcome"`
+	Error         string      `json:"error,omitempty"`
+	Outputs       []string    `json:"outputs,omitempty"`
+	Usage         quota.Usage `json:"usage"`
+	EstimatedCost float64     `json:"estimated_cost"`
+	DurationMS    int64       `json:"duration_ms"`
+}
+
+// Prices unit prices used to estimate the cost of a call
+type Prices struct {
+	PerCall      float64
+	PerCharacter float64
+	PerImage     float64
+	PerVideo     float64
+}
+
+// Cost returns the estimated cost of usage
+func (p Prices) Cost(usage quota.Usage) float64 {
+	return float64(usage.Calls)*p.PerCall +
+		float64(usage.Characters)*p.PerCharacter +
+		float64(usage.Images)*p.PerImage +
+		float64(usage.Videos)*p.PerVideo
+}
+
+// Config audit log configuration
+type Config struct {
+	// Path is the active log file, rotated files are written next to it
+	Path string
+	// MaxSize rotates the file before it grows past this many bytes, 0 disables size rotation
+	MaxSize int64
+	// TextLimit truncates string parameters to this many characters, 0 keeps them whole
+	TextLimit int
+	// Prices estimate the cost of every call
+	Prices Prices
+	// Secrets are literal values such as API keys that are redacted from parameters
+	Secrets []string
+}
+
+// Log an append-only JSONL audit log, rotated by size and at the start of every day
+type Log struct {
+	cfg      Config
+	redactor *logging.Redactor
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// Open opens the audit log for appending, creating it when it does not exist
+func Open(cfg Config) (*Log, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("audit log path is empty")
+	}
+	l := &Log{
+		cfg:      cfg,
+		redactor: logging.NewRedactor(cfg.Secrets...),
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Cost returns the estimated cost of usage at the configured prices
+func (l *Log) Cost(usage quota.Usage) float64 {
+	return l.cfg.Prices.Cost(usage)
+}
+
+// Path returns the active log file
+func (l *Log) Path() string {
+	return l.cfg.Path
+}
+
+// Write appends a record, rotating the file first when it is due
+func (l *Log) Write(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %v", err)
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("audit log is closed")
+	}
+	if l.rotationDue(record.Time, int64(len(data))) {
+		if err = l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit record: %v", err)
+	}
+	return nil
+}
+
+// Close closes the active log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Params normalizes the arguments of a tool call for the audit record.
+// Secrets and inline data are redacted and long texts are truncated.
+func (l *Log) Params(raw json.RawMessage) map[string]interface{} {
+	var params map[string]interface{}
+	if err := json.Unmarshal(raw, &params); err != nil || len(params) == 0 {
+		return nil
+	}
+	for key, value := range params {
+		params[key] = l.normalize(value)
+	}
+	return params
+}
+
+func (l *Log) normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return Truncate(l.redactor.String(v), l.cfg.TextLimit)
+	case []interface{}:
+		for i := range v {
+			v[i] = l.normalize(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = l.normalize(v[key])
+		}
+	}
+	return value
+}
+
+// Truncate shortens s to limit characters, noting the original length. A limit of 0 keeps s whole.
+func Truncate(s string, limit int) string {
+	if limit <= 0 {
+		return s
+	}
+	length := utf8.RuneCountInString(s)
+	if length <= limit {
+		return s
+	}
+	return fmt.Sprintf("%s... [%d chars]", string([]rune(s)[:limit]), length)
+}
+
+// rotationDue reports whether writing n bytes at now needs a new file, the caller holds the lock
+func (l *Log) rotationDue(now time.Time, n int64) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.cfg.MaxSize > 0 && l.size+n > l.cfg.MaxSize {
+		return true
+	}
+	y1, m1, d1 := l.opened.Date()
+	y2, m2, d2 := now.Date()
+	return y1 != y2 || m1 != m2 || d1 != d2
+}
+
+// rotate renames the active file with its opening time and starts a new one, the caller holds the lock
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %v", err)
+	}
+	l.file = nil
+
+	rotated := rotatedName(l.cfg.Path, l.opened)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			break
+		}
+		rotated = rotatedName(l.cfg.Path, l.opened.Add(time.Duration(i)*time.Second))
+	}
+	if err := os.Rename(l.cfg.Path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %v", err)
+	}
+	return l.open()
+}
+
+// open opens the active file, the caller holds the lock
+func (l *Log) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.cfg.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+	file, err := os.OpenFile(l.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit log: %v", err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+	l.opened = time.Now()
+	if l.size > 0 {
+		l.opened = info.ModTime()
+	}
+	return nil
+}
+
+// rotatedName returns the name of a file rotated at t, such as audit-20250102-150405.jsonl
+func rotatedName(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + t.Format("20060102-150405") + ext
+}
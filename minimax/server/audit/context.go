@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+type entryKey struct{}
+
+// Entry details of a tool call gathered while it runs, such as the
+// MiniMax trace IDs of its API requests and the files it produced
+type Entry struct {
+	mu       sync.Mutex
+	traceIDs []string
+	taskID   string
+	outputs  []string
+}
+
+// WithEntry starts gathering the details of a tool call in the context
+func WithEntry(ctx context.Context) (context.Context, *Entry) {
+	entry := &Entry{}
+	return context.WithValue(ctx, entryKey{}, entry), entry
+}
+
+// AddTraceID records the MiniMax trace ID of an API request, it is a no-op outside an audited call
+func AddTraceID(ctx context.Context, traceID string) {
+	if entry, ok := ctx.Value(entryKey{}).(*Entry); ok && traceID != "" {
+		entry.mu.Lock()
+		entry.traceIDs = append(entry.traceIDs, traceID)
+		entry.mu.Unlock()
+	}
+}
+
+// SetTaskID records the MiniMax task of an asynchronous call, it is a no-op outside an audited call
+func SetTaskID(ctx context.Context, taskID string) {
+	if entry, ok := ctx.Value(entryKey{}).(*Entry); ok {
+		entry.mu.Lock()
+		entry.taskID = taskID
+		entry.mu.Unlock()
+	}
+}
+
+// AddOutput records a file path or URL produced by the call, it is a no-op outside an audited call
+func AddOutput(ctx context.Context, location string) {
+	if entry, ok := ctx.Value(entryKey{}).(*Entry); ok && location != "" {
+		entry.mu.Lock()
+		entry.outputs = append(entry.outputs, location)
+		entry.mu.Unlock()
+	}
+}
+
+// Fill copies the gathered details into record
+func (e *Entry) Fill(record *Record) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	record.TraceIDs = append([]string(nil), e.traceIDs...)
+	record.TaskID = e.taskID
+	record.Outputs = append([]string(nil), e.outputs...)
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filter selects audit records, zero fields match every record
+type Filter struct {
+	Since   time.Time
+	Until   time.Time
+	Tool    string
+	Session string
+	Client  string
+	Outcome string
+	TaskID  string
+}
+
+// Match reports whether record passes the filter
+func (f Filter) Match(record *Record) bool {
+	switch {
+	case !f.Since.IsZero() && record.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !record.Time.Before(f.Until):
+		return false
+	case f.Tool != "" && record.Tool != f.Tool:
+		return false
+	case f.Session != "" && record.SessionID != f.Session:
+		return false
+	case f.Client != "" && record.Client != f.Client:
+		return false
+	case f.Outcome != "" && record.Outcome != f.Outcome:
+		return false
+	case f.TaskID != "" && record.TaskID != f.TaskID:
+		return false
+	}
+	return true
+}
+
+// Files returns the rotated files of the log at path from oldest to newest, followed by path itself
+func Files(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	rotated, err := filepath.Glob(strings.TrimSuffix(path, ext) + "-*" + ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %v", err)
+	}
+	sort.Strings(rotated)
+
+	files := rotated
+	if _, err = os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// Query calls fn with every record of the log at path and its rotated files that matches the filter, oldest first
+func Query(path string, filter Filter, fn func(record *Record, line []byte) error) error {
+	files, err := Files(path)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err = queryFile(file, filter, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func queryFile(path string, filter Filter, fn func(record *Record, line []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record Record
+		if err = json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("%s:%d: invalid audit record: %v", path, lineNo, err)
+		}
+		if !filter.Match(&record) {
+			continue
+		}
+		if err = fn(&record, line); err != nil {
+			return err
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log %s: %v", path, err)
+	}
+	return nil
+}
@@ -1 +1 @@
-GOOS=linux GOARCH=amd64 go build -v -o miniMaxMCPServer main.go
\ No newline at end of file
+GOOS=linux GOARCH=amd64 go build -v -o miniMaxMCPServer .
\ No newline at end of file
@@ -0,0 +1,331 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mcp/logging"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Modes of a cassette transport
+const (
+	// ModeRecord sends requests upstream and records every interaction, the cassette is replaced on Close
+	ModeRecord = "record"
+	// ModeReplay answers requests from the cassette
+	ModeReplay = "replay"
+)
+
+// DefaultMaxBodyBytes the largest response body recorded when Config.MaxBodyBytes is not set
+const DefaultMaxBodyBytes = 100 << 20
+
+// ErrNoInteraction is returned in strict replay mode for requests that were not recorded
+var ErrNoInteraction = errors.New("no recorded interaction")
+
+// volatileHeaders request headers that differ on every run and are not recorded
+var volatileHeaders = []string{"Traceparent", "Tracestate", "X-Request-Id"}
+
+// Message a recorded request or response. Text bodies are kept as is, binary ones in base64.
+type Message struct {
+	Method     string      `json:"method,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+// Interaction a recorded request and the response it received
+type Interaction struct {
+	Request  Message `json:"request"`
+	Response Message `json:"response"`
+
+	used bool
+}
+
+// Config cassette configuration
+type Config struct {
+	// Path is the cassette file
+	Path string
+	// Mode is ModeRecord or ModeReplay
+	Mode string
+	// Strict fails replayed requests without a recorded interaction instead of sending them upstream
+	Strict bool
+	// Secrets are literal values such as API keys that are scrubbed from the cassette
+	Secrets []string
+	// MaxBodyBytes caps the response bodies read into memory while recording, larger responses
+	// fail. Defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+}
+
+// Transport an http.RoundTripper that records interactions to a cassette file or replays them.
+// Requests match a recorded interaction by method, URL, Range header and body, with secrets
+// scrubbed and JSON bodies compared structurally. Each recorded interaction is replayed once,
+// in recording order. A recording is written to the cassette file by Close.
+type Transport struct {
+	cfg      Config
+	next     http.RoundTripper
+	redactor *logging.Redactor
+
+	mu           sync.Mutex
+	interactions []*Interaction
+}
+
+// Open creates a cassette transport, next sends the requests that are not replayed
+// and defaults to http.DefaultTransport. Replaying needs an existing cassette.
+func Open(cfg Config, next http.RoundTripper) (*Transport, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("cassette path is empty")
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	t := &Transport{
+		cfg:      cfg,
+		next:     next,
+		redactor: logging.NewRedactor(cfg.Secrets...),
+	}
+
+	switch cfg.Mode {
+	case ModeRecord:
+		return t, nil
+	case ModeReplay:
+		data, err := os.ReadFile(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %v", err)
+		}
+		var cassette struct {
+			Interactions []*Interaction `json:"interactions"`
+		}
+		if err = json.Unmarshal(data, &cassette); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %v", cfg.Path, err)
+		}
+		t.interactions = cassette.Interactions
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q, expected record or replay", cfg.Mode)
+	}
+}
+
+// RoundTrip records or replays a request
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	request := t.requestMessage(req, body)
+
+	if t.cfg.Mode == ModeReplay {
+		if interaction := t.match(request); interaction != nil {
+			return interaction.Response.response(req)
+		}
+		if t.cfg.Strict {
+			return nil, fmt.Errorf("%w in cassette %s for %s %s", ErrNoInteraction, t.cfg.Path, request.Method, request.URL)
+		}
+		return t.next.RoundTrip(req)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, t.cfg.MaxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response for cassette: %v", err)
+	}
+	if int64(len(respBody)) > t.cfg.MaxBodyBytes {
+		return nil, fmt.Errorf("response of %s %s exceeds %d bytes, too large to record", request.Method, request.URL, t.cfg.MaxBodyBytes)
+	}
+
+	response := Message{
+		StatusCode: resp.StatusCode,
+		Header:     t.scrubHeader(resp.Header),
+	}
+	response.setBody(respBody, t.redactor.Secrets)
+	t.record(&Interaction{Request: request, Response: response})
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	resp.ContentLength = int64(len(respBody))
+	return resp, nil
+}
+
+// Unused returns the recorded interactions that have not been replayed, so tests can
+// check that every expected request was made
+func (t *Transport) Unused() []Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var unused []Interaction
+	for _, interaction := range t.interactions {
+		if !interaction.used {
+			unused = append(unused, *interaction)
+		}
+	}
+	return unused
+}
+
+// requestMessage scrubs a request into its recorded form
+func (t *Transport) requestMessage(req *http.Request, body []byte) Message {
+	request := Message{
+		Method: req.Method,
+		URL:    t.redactor.Secrets(req.URL.String()),
+		Header: t.scrubHeader(req.Header),
+	}
+	for _, key := range volatileHeaders {
+		request.Header.Del(key)
+	}
+
+	// Multipart boundaries are random and uploads can be large, such bodies are not kept
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+	case mediaType == "application/json":
+		request.Body = t.redactor.String(canonicalJSON(body))
+	default:
+		request.setBody(body, t.redactor.String)
+	}
+	return request
+}
+
+// match returns the first unused interaction recorded for request and marks it used
+func (t *Transport) match(request Message) *Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, interaction := range t.interactions {
+		recorded := interaction.Request
+		if interaction.used ||
+			recorded.Method != request.Method ||
+			recorded.URL != request.URL ||
+			recorded.Header.Get("Range") != request.Header.Get("Range") ||
+			recorded.Body != request.Body ||
+			recorded.BodyBase64 != request.BodyBase64 {
+			continue
+		}
+		interaction.used = true
+		return interaction
+	}
+	return nil
+}
+
+// record appends an interaction to the recording
+func (t *Transport) record(interaction *Interaction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, interaction)
+}
+
+// Close writes the recorded interactions to the cassette file, replacing it. It does nothing in replay mode.
+func (t *Transport) Close() error {
+	if t.cfg.Mode != ModeRecord {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.MarshalIndent(map[string]interface{}{"interactions": t.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %v", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(t.cfg.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %v", err)
+	}
+	tmp := t.cfg.Path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %v", err)
+	}
+	if err = os.Rename(tmp, t.cfg.Path); err != nil {
+		return fmt.Errorf("failed to move cassette into place: %v", err)
+	}
+	return nil
+}
+
+// scrubHeader copies a header with secrets redacted from its values
+func (t *Transport) scrubHeader(header http.Header) http.Header {
+	scrubbed := make(http.Header, len(header))
+	for key, values := range header {
+		for _, value := range values {
+			scrubbed.Add(key, t.redactor.Secrets(value))
+		}
+	}
+	return scrubbed
+}
+
+// setBody stores a body as scrubbed text, or as base64 when it is binary
+func (m *Message) setBody(body []byte, scrub func(string) string) {
+	if len(body) == 0 {
+		return
+	}
+	if utf8.Valid(body) {
+		m.Body = scrub(string(body))
+		return
+	}
+	m.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+}
+
+// response builds the replayed response for req
+func (m *Message) response(req *http.Request) (*http.Response, error) {
+	body := []byte(m.Body)
+	if m.BodyBase64 != "" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(m.BodyBase64); err != nil {
+			return nil, fmt.Errorf("invalid cassette response body: %v", err)
+		}
+	}
+	header := m.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", m.StatusCode, http.StatusText(m.StatusCode)),
+		StatusCode:    m.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// readBody reads the request body and puts it back so the request can still be sent
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request for cassette: %v", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// canonicalJSON re-encodes a JSON body with sorted keys so equal payloads compare equal
+func canonicalJSON(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(data)
+}
@@ -0,0 +1,152 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testSecret = "secret-api-key"
+
+// newUpstream serves a JSON answer echoing the request path and a binary download
+func newUpstream(t *testing.T, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/download" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0x00, 0xff, 0xfe, 0x01})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"path":"`+r.URL.Path+`","key":"`+testSecret+`"}`)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// get sends a request through transport and returns the response body
+func get(t *testing.T, transport http.RoundTripper, method, url, body string) (string, error) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testSecret)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), nil
+}
+
+func TestRecordAndReplayStrict(t *testing.T) {
+	var hits atomic.Int32
+	upstream := newUpstream(t, &hits)
+	path := filepath.Join(t.TempDir(), "api.json")
+
+	recorder, err := Open(Config{Path: path, Mode: ModeRecord, Secrets: []string{testSecret}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := make(map[string]string)
+	for _, call := range []struct{ method, path, body string }{
+		{http.MethodPost, "/v1/t2a_v2", `{"text":"hello","model":"speech-02-hd"}`},
+		{http.MethodGet, "/download", ""},
+	} {
+		body, err := get(t, recorder, call.method, upstream.URL+call.path, call.body)
+		if err != nil {
+			t.Fatalf("recording %s: %v", call.path, err)
+		}
+		recorded[call.path] = body
+	}
+	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("cassette written before Close: %v", err)
+	}
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), testSecret) {
+		t.Fatal("cassette contains the secret")
+	}
+
+	recordedHits := hits.Load()
+	replayer, err := Open(Config{Path: path, Mode: ModeReplay, Strict: true, Secrets: []string{testSecret}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unused := replayer.Unused(); len(unused) != 2 {
+		t.Fatalf("got %d unused interactions before replay, want 2", len(unused))
+	}
+
+	// JSON bodies match structurally, whatever the key order
+	body, err := get(t, replayer, http.MethodPost, upstream.URL+"/v1/t2a_v2", `{"model":"speech-02-hd","text":"hello"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.ReplaceAll(recorded["/v1/t2a_v2"], testSecret, "[REDACTED]"); body != want {
+		t.Fatalf("replayed body %q, want %q", body, want)
+	}
+	if unused := replayer.Unused(); len(unused) != 1 || !strings.HasSuffix(unused[0].Request.URL, "/download") {
+		t.Fatalf("got unused interactions %+v, want the download", unused)
+	}
+
+	if body, err = get(t, replayer, http.MethodGet, upstream.URL+"/download", ""); err != nil {
+		t.Fatal(err)
+	}
+	if body != recorded["/download"] {
+		t.Fatalf("replayed binary body %q, want %q", body, recorded["/download"])
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Fatalf("got %d unused interactions after replay, want 0", len(unused))
+	}
+
+	// Each interaction is replayed once, and strict mode never goes upstream
+	if _, err = get(t, replayer, http.MethodGet, upstream.URL+"/download", ""); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("got %v for a replayed request sent again, want ErrNoInteraction", err)
+	}
+	if _, err = get(t, replayer, http.MethodPost, upstream.URL+"/v1/t2a_v2", `{"text":"other"}`); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("got %v for an unrecorded request, want ErrNoInteraction", err)
+	}
+	if hits.Load() != recordedHits {
+		t.Fatalf("upstream got %d requests during replay", hits.Load()-recordedHits)
+	}
+}
+
+func TestRecordRejectsLargeBodies(t *testing.T) {
+	var hits atomic.Int32
+	upstream := newUpstream(t, &hits)
+
+	recorder, err := Open(Config{Path: filepath.Join(t.TempDir(), "api.json"), Mode: ModeRecord, MaxBodyBytes: 3}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = get(t, recorder, http.MethodGet, upstream.URL+"/download", ""); err == nil || !strings.Contains(err.Error(), "too large to record") {
+		t.Fatalf("got %v for a body over the limit, want a size error", err)
+	}
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
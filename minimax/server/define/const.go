@@ -16,13 +16,30 @@ const (
 	DefaultT2IModel      = "image-01"
 	DefaultT2AModel      = "speech-02-hd"
 	DefaultVCModel       = "speech-02-hd"
-	DefaultT2VModel      = "S2V-01"
+	DefaultT2VModel      = "T2V-01"
+	DefaultI2VModel      = "I2V-01"
+	DefaultS2VModel      = "S2V-01"
 	ResourceModeURL      = "url"
 	ResourceModeData     = "data"
 	DefaultModel         = "MiniMaxAbility"
 	DefaultChatModel     = "abab5.5-chat"
 )
 
+// Music generation defaults
+const (
+	DefaultMusicModel      = "music-1.5"
+	DefaultMusicRefModel   = "music-01"
+	DefaultMusicSampleRate = 44100
+	DefaultMusicBitrate    = 256000
+)
+
+// Embedding defaults
+const (
+	DefaultEmbeddingModel     = "embo-01"
+	DefaultEmbeddingBatchSize = 16
+	DefaultSearchTopK         = 5
+)
+
 // Environment variable keys
 const (
 	EnvMinimaxAPIKey      = "MINIMAX_API_KEY"
@@ -47,3 +64,8 @@ const (
 	Stdio      ServerMode = "stdio"
 	Streamable ServerMode = "streamable"
 )
+
+// Concurrency defaults
+const (
+	DefaultMaxQueue = 32
+)
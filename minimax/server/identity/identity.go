@@ -0,0 +1,43 @@
+package identity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+type clientKey struct{}
+
+// HTTPMiddleware records the bearer token of HTTP requests as the client identity.
+// The MCP SDK keeps request context values when it dispatches tool calls, so the
+// identity is available to tool middlewares in sse and streamable mode.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			r = r.WithContext(context.WithValue(r.Context(), clientKey{}, ClientID(token)))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientID derives a stable identifier from a token, the token itself is never stored or logged
+func ClientID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "token-" + hex.EncodeToString(sum[:6])
+}
+
+// Client returns the client identity of the context, or an empty string for unauthenticated clients
+func Client(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+	return client
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
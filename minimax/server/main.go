@@ -1,9 +1,30 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mcp/logging"
+	"mcp/minimax/server/admin"
+	"mcp/minimax/server/audit"
+	"mcp/minimax/server/cassette"
 	"mcp/minimax/server/define"
+	"mcp/minimax/server/identity"
+	"mcp/minimax/server/metrics"
 	"mcp/minimax/server/minimax"
+	"mcp/minimax/server/openai"
+	"mcp/minimax/server/provider"
+	"mcp/minimax/server/quota"
+	"mcp/minimax/server/storage"
+	"mcp/minimax/server/tracing"
+	"mcp/minimax/server/vectorstore"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
 	"github.com/ThinkInAIXYZ/go-mcp/server"
@@ -12,27 +33,73 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "query_audit" {
+		os.Exit(queryAudit(os.Args[2:]))
+	}
+	os.Exit(run())
+}
+
+// run starts the server and returns the exit code once it stopped, so that deferred cleanups such as
+// flushing logs and traces run on every exit
+func run() int {
 	// Load configuration from ini file
 	cfg, err := ini.Load("config.ini")
 	if err != nil {
-		log.Fatalf("Failed to load config file: %v", err)
+		return failed("Failed to load config file", "error", err)
 	}
 
 	// Read necessary configuration variables
 	apiKey := cfg.Section("Minimax").Key("APIKey").String()
 	if apiKey == "" {
-		log.Fatalf("APIKey not set in config file")
+		return failed("APIKey not set in config file")
 	}
 
 	apiHost := cfg.Section("Minimax").Key("APIHost").String()
 	if apiHost == "" {
-		log.Fatalf("APIHost not set in config file")
+		return failed("APIHost not set in config file")
 	}
 
 	mode := define.ServerMode(cfg.Section("Minimax").Key("Mode").String())
 	if !mode.Valid() {
-		log.Fatalf("Mode not set in config file")
+		return failed("Mode not set in config file")
+	}
+
+	// Structured logging, stdout is reserved for MCP messages in stdio mode
+	logger, logCloser, err := logging.New(logging.Config{
+		Level:   cfg.Section("Log").Key("Level").String(),
+		Format:  cfg.Section("Log").Key("Format").String(),
+		Output:  cfg.Section("Log").Key("Output").String(),
+		Stdio:   mode == define.Stdio,
+		Secrets: []string{apiKey},
+	})
+	if err != nil {
+		return failed("Failed to set up logging", "error", err)
 	}
+	defer logCloser.Close()
+	slog.SetDefault(logger)
+	sdkLogger := logging.SDKLogger(logger)
+
+	// OpenTelemetry tracing, disabled unless an exporter is configured
+	tracingSection := cfg.Section("Tracing")
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    tracingSection.Key("Exporter").String(),
+		Endpoint:    tracingSection.Key("Endpoint").String(),
+		Insecure:    tracingSection.Key("Insecure").MustBool(false),
+		File:        tracingSection.Key("File").String(),
+		SampleRatio: tracingSection.Key("SampleRatio").MustFloat64(1),
+		ServiceName: tracingSection.Key("ServiceName").String(),
+	})
+	if err != nil {
+		return failed("Failed to set up tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+
 	addr := cfg.Section("Minimax").Key("Addr").String()
 	if addr == "" {
 		addr = "127.0.0.1:8080"
@@ -43,30 +110,240 @@ func main() {
 		resourceMode = "url" // Default value
 	}
 
+	// Download limits for audio, image and video files fetched from MiniMax
+	downloadSection := cfg.Section("Download")
+	downloadOptions := storage.DownloadOptions{
+		MaxBytes: downloadSection.Key("MaxSizeMB").MustInt64(storage.DefaultDownloadMaxBytes>>20) << 20,
+		Timeout:  downloadSection.Key("Timeout").MustDuration(storage.DefaultDownloadTimeout),
+		Retries:  downloadSection.Key("Retries").MustInt(storage.DefaultDownloadRetries),
+	}
+
+	// Optional local vector index for the document retrieval tools
+	var index *vectorstore.Index
+	if cfg.Section("Embedding").Key("IndexEnabled").MustBool(false) {
+		indexPath := cfg.Section("Embedding").Key("IndexPath").String()
+		if indexPath == "" {
+			indexPath = filepath.Join(storage.BuildOutputPath(), "vector_index.json")
+		}
+		index, err = vectorstore.Open(indexPath)
+		if err != nil {
+			return failed("Failed to open vector index", "error", err)
+		}
+	}
+
+	// Video tasks interrupted by a shutdown are stored here and resumed on the next start
+	pendingTasksFile := cfg.Section("Shutdown").Key("PendingTasksFile").String()
+	if pendingTasksFile == "" {
+		pendingTasksFile = filepath.Join(storage.BuildOutputPath(), "pending_tasks.json")
+	}
+	pendingTasks, err := minimax.OpenPendingTasks(pendingTasksFile)
+	if err != nil {
+		return failed("Failed to open pending tasks", "error", err)
+	}
+	gracePeriod := cfg.Section("Shutdown").Key("GracePeriod").MustDuration(30 * time.Second)
+
+	// Concurrency limits, tool calls beyond them wait in a bounded FIFO queue
+	concurrencySection := cfg.Section("Concurrency")
+	concurrency := minimax.ConcurrencyConfig{
+		Global:       concurrencySection.Key("Global").MustInt(0),
+		PerTool:      make(map[string]int),
+		MaxQueue:     concurrencySection.Key("MaxQueue").MustInt(define.DefaultMaxQueue),
+		QueueTimeout: concurrencySection.Key("QueueTimeout").MustDuration(0),
+	}
+	for _, key := range cfg.Section("ConcurrencyPerTool").Keys() {
+		concurrency.PerTool[key.Name()] = key.MustInt(0)
+	}
+
+	// Usage quotas per session and per client token in fixed windows
+	quotaSection := cfg.Section("Quota")
+	quotaLimits := func(prefix string) quota.Limits {
+		return quota.Limits{
+			Calls:      quotaSection.Key(prefix + "Calls").MustInt(0),
+			Characters: quotaSection.Key(prefix + "Characters").MustInt(0),
+			Images:     quotaSection.Key(prefix + "Images").MustInt(0),
+			Videos:     quotaSection.Key(prefix + "Videos").MustInt(0),
+		}
+	}
+	quotas, err := quota.New(quota.Config{
+		Window:  quotaSection.Key("Window").MustDuration(time.Hour),
+		Session: quotaLimits("Session"),
+		Client:  quotaLimits("Client"),
+		Path:    quotaSection.Key("Path").String(),
+	})
+	if err != nil {
+		return failed("Failed to set up quotas", "error", err)
+	}
+
+	// Audit log of every tool call, read back with the query_audit subcommand
+	var auditLog *audit.Log
+	auditSection := cfg.Section("Audit")
+	if auditSection.Key("Enabled").MustBool(false) {
+		auditLog, err = audit.Open(audit.Config{
+			Path:      auditPath(cfg),
+			MaxSize:   auditSection.Key("MaxSizeMB").MustInt64(100) << 20,
+			TextLimit: auditSection.Key("TextLimit").MustInt(200),
+			Prices: audit.Prices{
+				PerCall:      auditSection.Key("PricePerCall").MustFloat64(0),
+				PerCharacter: auditSection.Key("PricePerCharacter").MustFloat64(0),
+				PerImage:     auditSection.Key("PricePerImage").MustFloat64(0),
+				PerVideo:     auditSection.Key("PricePerVideo").MustFloat64(0),
+			},
+			Secrets: []string{apiKey},
+		})
+		if err != nil {
+			return failed("Failed to open audit log", "error", err)
+		}
+		defer auditLog.Close()
+	}
+
 	// Create Minimax API client
 	apiClient := &minimax.APIClient{
 		APIKey:  apiKey,
 		APIHost: apiHost,
 	}
 
+	// Record or replay the API and download traffic, for integration tests without the real API
+	cassetteSection := cfg.Section("Cassette")
+	if cassetteMode := cassetteSection.Key("Mode").String(); cassetteMode != "" && cassetteMode != "off" {
+		recorder, err := cassette.Open(cassette.Config{
+			Path:         cassetteSection.Key("Path").String(),
+			Mode:         cassetteMode,
+			Strict:       cassetteSection.Key("Strict").MustBool(false),
+			Secrets:      []string{apiKey},
+			MaxBodyBytes: downloadOptions.MaxBytes,
+		}, nil)
+		if err != nil {
+			return failed("Failed to open cassette", "error", err)
+		}
+		defer func() {
+			if err := recorder.Close(); err != nil {
+				slog.Error("Failed to save cassette", "error", err)
+			}
+		}()
+		slog.Warn("API traffic goes through a cassette", "mode", cassetteMode, "path", cassetteSection.Key("Path").String())
+		apiClient.Transport = recorder
+		downloadOptions.Client = &http.Client{Transport: recorder}
+	}
+
+	// Media providers per tool, MiniMax unless [Providers] selects another one
+	providers := map[string]any{provider.MiniMax: minimax.NewProvider(apiClient)}
+	if openAIKey := cfg.Section("OpenAI").Key("APIKey").String(); openAIKey != "" {
+		providers[provider.OpenAI] = &openai.Provider{
+			APIKey:      openAIKey,
+			BaseURL:     cfg.Section("OpenAI").Key("BaseURL").String(),
+			SpeechModel: cfg.Section("OpenAI").Key("SpeechModel").String(),
+			SpeechVoice: cfg.Section("OpenAI").Key("SpeechVoice").String(),
+			ImageModel:  cfg.Section("OpenAI").Key("ImageModel").String(),
+			Transport:   apiClient.Transport,
+		}
+	}
+	providerName := func(tool string) string {
+		return cfg.Section("Providers").Key(tool).MustString(provider.MiniMax)
+	}
+	speechProvider, err := provider.Select[provider.SpeechProvider](providers, "text_to_audio", providerName("text_to_audio"))
+	if err != nil {
+		return failed("Failed to select provider", "error", err)
+	}
+	imageProvider, err := provider.Select[provider.ImageProvider](providers, "text_to_image", providerName("text_to_image"))
+	if err != nil {
+		return failed("Failed to select provider", "error", err)
+	}
+	videoProvider, err := provider.Select[provider.VideoProvider](providers, "generate_video", providerName("generate_video"))
+	if err != nil {
+		return failed("Failed to select provider", "error", err)
+	}
+	voiceCloneProvider, err := provider.Select[provider.VoiceCloneProvider](providers, "voice_clone", providerName("voice_clone"))
+	if err != nil {
+		return failed("Failed to select provider", "error", err)
+	}
+
 	apiServer := &minimax.MCPServer{
 		Client:       apiClient,
 		ResourceMode: resourceMode,
+		Download:     downloadOptions,
+		Index:        index,
+		Tracker:      minimax.NewTracker(),
+		PendingTasks: pendingTasks,
+		Limiter:      minimax.NewLimiter(concurrency),
+		Speech:       speechProvider,
+		Image:        imageProvider,
+		Video:        videoProvider,
+		VoiceClone:   voiceCloneProvider,
 	}
+	drain := minimax.NewDrain()
+	apiServer.Drain = drain
 
-	// Create MCP transport layer
-	var transportServer transport.ServerTransport
+	// Create MCP transport layer, the HTTP transports are served by our own mux
+	// so that metrics can share the MCP address
+	var (
+		transportServer transport.ServerTransport
+		mux             *http.ServeMux
+		httpServers     []*http.Server
+		// serveErr receives the failure of a listener, there are at most three: MCP, metrics and admin
+		serveErr = make(chan error, 3)
+	)
 	switch mode {
 	case define.SSE:
-		transportServer, err = transport.NewSSEServerTransport(addr)
+		var handler *transport.SSEHandler
+		transportServer, handler, err = transport.NewSSEServerTransportAndHandler("/message",
+			transport.WithSSEServerTransportAndHandlerOptionLogger(sdkLogger))
 		if err != nil {
-			log.Fatalf("Failed to create SSE transport: %v", err)
+			return failed("Failed to create SSE transport", "error", err)
 		}
+		mux = http.NewServeMux()
+		mux.Handle("/sse", handler.HandleSSE())
+		mux.Handle("/message", handler.HandleMessage())
 	case define.Streamable:
-		transportServer = transport.NewStreamableHTTPServerTransport(addr,
-			transport.WithStreamableHTTPServerTransportOptionStateMode(transport.Stateful))
+		var handler *transport.StreamableHTTPHandler
+		transportServer, handler, err = transport.NewStreamableHTTPServerTransportAndHandler(
+			transport.WithStreamableHTTPServerTransportAndHandlerOptionStateMode(transport.Stateful),
+			transport.WithStreamableHTTPServerTransportAndHandlerOptionLogger(sdkLogger))
+		if err != nil {
+			return failed("Failed to create streamable HTTP transport", "error", err)
+		}
+		mux = http.NewServeMux()
+		mux.Handle("/mcp", handler.HandleMCP())
 	default:
-		transportServer = transport.NewStdioServerTransport()
+		transportServer = transport.NewStdioServerTransport(transport.WithStdioServerOptionLogger(sdkLogger))
+	}
+
+	// Prometheus metrics, next to the MCP endpoint unless a separate address is configured
+	if cfg.Section("Metrics").Key("Enabled").MustBool(false) {
+		metricsAddr := cfg.Section("Metrics").Key("Addr").String()
+		switch {
+		case metricsAddr != "":
+			metricsMux := http.NewServeMux()
+			metricsMux.Handle("/metrics", metrics.Handler())
+			httpServers = append(httpServers, serveHTTP("metrics", metricsAddr, metricsMux, serveErr))
+		case mux != nil:
+			mux.Handle("/metrics", metrics.Handler())
+		default:
+			return failed("Metrics.Addr must be set in stdio mode")
+		}
+	}
+
+	// Health, readiness and admin endpoints, next to the MCP endpoint and on the admin address when configured
+	adminServer := &admin.Server{
+		Client:     apiClient,
+		Tracker:    apiServer.Tracker,
+		Config:     cfg,
+		OutputPath: storage.BuildOutputPath(),
+		Token:      cfg.Section("Admin").Key("Token").String(),
+		Mode:       string(mode),
+		Drain:      drain,
+	}
+	if mux != nil {
+		adminServer.Register(mux)
+		if adminServer.Token == "" {
+			slog.Info("/admin is not served on the MCP address without Admin.Token")
+		}
+	}
+	if adminAddr := cfg.Section("Admin").Key("Addr").String(); adminAddr != "" {
+		adminMux := http.NewServeMux()
+		if err = adminServer.RegisterAdminAddr(adminMux, adminAddr); err != nil {
+			return failed("Refusing to expose /admin without a token", "error", err)
+		}
+		httpServers = append(httpServers, serveHTTP("admin", adminAddr, adminMux, serveErr))
 	}
 
 	// Create MCP server
@@ -75,15 +352,116 @@ func main() {
 			Name:    "MiniMax MCP",
 			Version: "1.0.0",
 		}),
+		server.WithLogger(sdkLogger),
 	)
 	if err != nil {
-		log.Fatalf("Failed to create MCP server: %v", err)
+		return failed("Failed to create MCP server", "error", err)
 	}
+	apiServer.Notifier = mcpServer
+
+	// Middlewares apply to tools registered after them, the first one is outermost.
+	// Metrics come first so that calls rejected while draining or by quotas and the queue time are measured.
+	mcpServer.Use(
+		minimax.MetricsMiddleware(),
+		drain.Middleware(),
+		minimax.TracingMiddleware(),
+		minimax.RequestLogMiddleware(),
+		apiServer.Tracker.Middleware(),
+		minimax.AuditMiddleware(auditLog),
+		minimax.QuotaMiddleware(quotas),
+		apiServer.Limiter.Middleware(),
+	)
 
 	// Register tools
-	minimax.RegisterTools(mcpServer, apiServer)
+	err = minimax.RegisterTools(mcpServer, apiServer, minimax.ToolConfig{
+		Enable:       cfg.Section("Tools").Key("Enable").Strings(","),
+		Disable:      cfg.Section("Tools").Key("Disable").Strings(","),
+		Descriptions: cfg.Section("ToolDescriptions").KeysHash(),
+	})
+	if err != nil {
+		return failed("Failed to register tools", "error", err)
+	}
 
-	if err = mcpServer.Run(); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+	if mux != nil {
+		httpServers = append(httpServers, serveHTTP("MCP", addr, identity.HTTPMiddleware(mux), serveErr))
 	}
+
+	recoveryCtx, cancelRecovery := context.WithCancel(context.Background())
+	defer cancelRecovery()
+	apiServer.RecoverVideoTasks(recoveryCtx)
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- mcpServer.Run()
+	}()
+
+	exitCode := 0
+	select {
+	case err = <-runErr:
+		if err != nil {
+			return failed("Failed to run server", "error", err)
+		}
+		return 0
+	case err = <-serveErr:
+		slog.Error("HTTP server failed, shutting down", "error", err)
+		exitCode = 1
+	case <-signalCtx.Done():
+	}
+	// A second signal terminates the process immediately
+	stopSignals()
+
+	slog.Info("Shutting down, waiting for in-flight tool calls",
+		"grace_period", gracePeriod, "in_flight", len(apiServer.Tracker.Jobs()))
+	graceCtx, cancelGrace := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancelGrace()
+	if !drain.Shutdown(graceCtx) {
+		slog.Warn("Grace period expired, cancelled the remaining tool calls")
+	}
+	cancelRecovery()
+	if tasks := pendingTasks.List(); len(tasks) > 0 {
+		slog.Info("Pending video tasks saved for recovery", "count", len(tasks), "path", pendingTasksFile)
+	}
+
+	// Every tool call has returned, the transport and listeners can close
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	if err = mcpServer.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Failed to shut down MCP server", "error", err)
+	}
+	for _, httpServer := range httpServers {
+		if err = httpServer.Shutdown(shutdownCtx); err != nil {
+			httpServer.Close()
+		}
+	}
+	select {
+	case <-runErr:
+	case <-shutdownCtx.Done():
+	}
+	slog.Info("Server stopped")
+	return exitCode
+}
+
+// serveHTTP starts serving handler on addr, the failure of the listener is sent to errs
+func serveHTTP(name, addr string, handler http.Handler, errs chan<- error) *http.Server {
+	slog.Info("Starting HTTP server", "server", name, "addr", addr)
+	httpServer := &http.Server{
+		Addr:        addr,
+		Handler:     handler,
+		IdleTimeout: time.Minute,
+	}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("%s server on %s: %v", name, addr, err)
+		}
+	}()
+	return httpServer
+}
+
+// failed logs an error and returns the exit code of a failed start
+func failed(msg string, args ...any) int {
+	slog.Error(msg, args...)
+	return 1
 }
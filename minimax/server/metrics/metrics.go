@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "minimax_mcp"
+
+// Tool call outcomes used as the status label of ToolCalls
+const (
+	StatusOK     = "ok"
+	StatusError  = "error"
+	StatusFailed = "failed"
+)
+
+// Registry holds every collector of the server, it is separate from the global
+// registry so that only the metrics below and the process metrics are exposed
+var Registry = prometheus.NewRegistry()
+
+var (
+	// ToolCalls counts tool calls by tool and outcome.
+	// "error" is a tool result flagged as error, "failed" a handler error.
+	ToolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tool_calls_total",
+		Help:      "MCP tool calls by tool and outcome.",
+	}, []string{"tool", "status"})
+
+	// ToolCallDuration observes tool call durations by tool
+	ToolCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tool_call_duration_seconds",
+		Help:      "MCP tool call duration by tool.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"tool"})
+
+	// APIRequests counts MiniMax API requests by endpoint and HTTP status code, "error" when no response was received
+	APIRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "MiniMax API requests by endpoint and HTTP status code.",
+	}, []string{"endpoint", "code"})
+
+	// APIRequestDuration observes MiniMax API latency by endpoint
+	APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "MiniMax API request latency by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	// VideoJobsInProgress is the number of video generation tasks being polled
+	VideoJobsInProgress = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "video_jobs_in_progress",
+		Help:      "Video generation tasks submitted and not yet finished.",
+	})
+
+	// VideoPollDuration observes the time from task submission until the task finished or polling gave up
+	VideoPollDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "video_poll_duration_seconds",
+		Help:      "Time spent polling video generation tasks by final status.",
+		Buckets:   []float64{30, 60, 120, 180, 240, 300, 420, 600},
+	}, []string{"status"})
+
+	// DownloadedBytes counts bytes of audio, image, video and file downloads
+	DownloadedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloaded_bytes_total",
+		Help:      "Bytes downloaded from MiniMax and other remote URLs.",
+	})
+
+	// Downloads counts downloads by outcome
+	Downloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloads_total",
+		Help:      "Downloads by outcome.",
+	}, []string{"status"})
+
+	// QueueDepth is the number of tool calls waiting for a concurrency slot
+	QueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Tool calls waiting for a concurrency slot.",
+	})
+
+	// QueueWait observes how long queued tool calls waited for a slot
+	QueueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "queue_wait_seconds",
+		Help:      "Time queued tool calls waited for a concurrency slot by tool.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"tool"})
+
+	// BusyRejections counts tool calls rejected because the queue was full or the queue timeout expired
+	BusyRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "busy_rejections_total",
+		Help:      "Tool calls rejected as server busy by tool.",
+	}, []string{"tool"})
+
+	// BudgetRejections counts tool calls rejected because a quota or budget was exhausted
+	BudgetRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "budget_rejections_total",
+		Help:      "Tool calls rejected by quotas and budgets by scope.",
+	}, []string{"scope"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ToolCalls,
+		ToolCallDuration,
+		APIRequests,
+		APIRequestDuration,
+		VideoJobsInProgress,
+		VideoPollDuration,
+		DownloadedBytes,
+		Downloads,
+		QueueDepth,
+		QueueWait,
+		BusyRejections,
+		BudgetRejections,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveAPIRequest records a MiniMax API request, code is 0 when no response was received
+func ObserveAPIRequest(endpoint string, code int, duration time.Duration) {
+	// Query strings carry task and file ids, they must not become label values
+	endpoint, _, _ = strings.Cut(endpoint, "?")
+
+	status := "error"
+	if code != 0 {
+		status = strconv.Itoa(code)
+	}
+	APIRequests.WithLabelValues(endpoint, status).Inc()
+	APIRequestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
@@ -0,0 +1,73 @@
+package minimax
+
+import (
+	"context"
+	"log/slog"
+	"mcp/logging"
+	"mcp/minimax/server/audit"
+	"mcp/minimax/server/identity"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+)
+
+// AuditMiddleware writes an audit record for every tool call, including calls rejected
+// by quotas or concurrency limits. It relies on RequestLogMiddleware having assigned
+// the request ID. A nil log disables auditing.
+func AuditMiddleware(log *audit.Log) server.ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		if log == nil {
+			return next
+		}
+		return func(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+			ctx, entry := audit.WithEntry(ctx)
+			ctx, keep := withQuotaCharge(ctx)
+			start := time.Now()
+			result, err := next(ctx, req)
+
+			sessionID, _ := server.GetSessionIDFromCtx(ctx)
+			usage := estimateUsage(req)
+			record := &audit.Record{
+				Time:       start.UTC(),
+				RequestID:  logging.RequestID(ctx),
+				SessionID:  sessionID,
+				Client:     identity.Client(ctx),
+				Tool:       req.Name,
+				Params:     log.Params(req.RawArguments),
+				Outcome:    audit.OutcomeOK,
+				Usage:      usage,
+				DurationMS: time.Since(start).Milliseconds(),
+			}
+			entry.Fill(record)
+			switch {
+			case err != nil:
+				record.Outcome = audit.OutcomeFailed
+				record.Error = err.Error()
+			case result != nil && result.IsError:
+				record.Outcome = audit.OutcomeError
+				record.Error = resultText(result)
+			}
+			// Like quotas, calls that end in an error are not charged unless the handler kept the charge
+			if record.Outcome == audit.OutcomeOK || keep.Load() {
+				record.EstimatedCost = log.Cost(usage)
+			}
+
+			if writeErr := log.Write(record); writeErr != nil {
+				slog.ErrorContext(ctx, "Failed to write audit record", "error", writeErr)
+			}
+			return result, err
+		}
+	}
+}
+
+// resultText returns the text content of a tool result
+func resultText(result *protocol.CallToolResult) string {
+	var text string
+	for _, content := range result.Content {
+		if textContent, ok := content.(*protocol.TextContent); ok {
+			text += textContent.Text
+		}
+	}
+	return text
+}
@@ -0,0 +1,168 @@
+package minimax
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ChatMessage a single message of a chat conversation
+type ChatMessage struct {
+	Role    string `json:"role" description:"The role of the message author. Values range [\"system\", \"user\", \"assistant\"]." enum:"system,user,assistant"`
+	Content string `json:"content" description:"The content of the message."`
+	Name    string `json:"name,omitempty" description:"An optional name of the author."`
+}
+
+// ChatCompletionRequest chat completion API request
+type ChatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+// ChatCompletionChoice a single choice of a chat completion response
+type ChatCompletionChoice struct {
+	Index        int         `json:"index"`
+	Message      ChatMessage `json:"message"`
+	Delta        ChatMessage `json:"delta"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// ChatCompletionUsage token usage of a chat completion
+type ChatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatCompletionResponse chat completion API response
+type ChatCompletionResponse struct {
+	ID       string                 `json:"id"`
+	Model    string                 `json:"model"`
+	Choices  []ChatCompletionChoice `json:"choices"`
+	Usage    *ChatCompletionUsage   `json:"usage,omitempty"`
+	BaseResp struct {
+		StatusCode int    `json:"status_code"`
+		StatusMsg  string `json:"status_msg"`
+	} `json:"base_resp"`
+}
+
+// Content returns the text of the first choice
+func (r *ChatCompletionResponse) Content() string {
+	if len(r.Choices) == 0 {
+		return ""
+	}
+	return r.Choices[0].Message.Content
+}
+
+// ChatCompletion sends a non-streaming chat completion request
+func (c *APIClient) ChatCompletion(ctx context.Context, chatReq *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	chatReq.Stream = false
+
+	resp, err := c.postChat(ctx, chatReq, time.Second*120)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result ChatCompletionResponse
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("response parsing failed: %v", err)
+	}
+	if result.BaseResp.StatusCode != 0 {
+		return nil, fmt.Errorf("API error: status_code=%d, message=%s", result.BaseResp.StatusCode, result.BaseResp.StatusMsg)
+	}
+	return &result, nil
+}
+
+// ChatCompletionStream sends a streaming chat completion request, onDelta is called for every content chunk.
+// The returned response carries the concatenated message in its first choice.
+func (c *APIClient) ChatCompletionStream(ctx context.Context, chatReq *ChatCompletionRequest, onDelta func(string)) (*ChatCompletionResponse, error) {
+	chatReq.Stream = true
+
+	resp, err := c.postChat(ctx, chatReq, time.Minute*10)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		result  ChatCompletionResponse
+		content strings.Builder
+		finish  string
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk ChatCompletionResponse
+		if err = json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("stream chunk parsing failed: %v", err)
+		}
+		if chunk.BaseResp.StatusCode != 0 {
+			return nil, fmt.Errorf("API error: status_code=%d, message=%s", chunk.BaseResp.StatusCode, chunk.BaseResp.StatusMsg)
+		}
+
+		result.ID, result.Model = chunk.ID, chunk.Model
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			finish = choice.FinishReason
+		}
+		// Only deltas are accumulated, the final chunk repeats the full message
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("stream read failed: %v", err)
+	}
+
+	result.Choices = []ChatCompletionChoice{{
+		Message:      ChatMessage{Role: "assistant", Content: content.String()},
+		FinishReason: finish,
+	}}
+	return &result, nil
+}
+
+// postChat posts a chat completion request and checks the HTTP status
+func (c *APIClient) postChat(ctx context.Context, chatReq *ChatCompletionRequest, timeout time.Duration) (*http.Response, error) {
+	const endpoint = "/v1/text/chatcompletion_v2"
+
+	jsonBytes, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("JSON encoding failed: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.APIHost+endpoint, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.do(req, endpoint, timeout)
+}
@@ -2,12 +2,24 @@ package minimax
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"log/slog"
+	"mcp/logging"
+	"mcp/minimax/server/audit"
+	"mcp/minimax/server/metrics"
+	"mcp/minimax/server/tracing"
 	"net/http"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // APIError API error structure
@@ -24,42 +36,44 @@ func (e *APIError) Error() string {
 type APIClient struct {
 	APIKey  string
 	APIHost string
+	// Transport sends the API requests, http.DefaultTransport when nil.
+	// It is replaced by a cassette to record or replay API traffic.
+	Transport http.RoundTripper
 }
 
 // Post sends a POST request to the MiniMax API
-func (c *APIClient) Post(endpoint string, jsonData interface{}) (map[string]interface{}, error) {
-	url := fmt.Sprintf("%s%s", c.APIHost, endpoint)
-
+func (c *APIClient) Post(ctx context.Context, endpoint string, jsonData interface{}) (map[string]interface{}, error) {
 	jsonBytes, err := json.Marshal(jsonData)
 	if err != nil {
 		return nil, fmt.Errorf("JSON encoding failed: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.APIHost+endpoint, bytes.NewBuffer(jsonBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
-
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
 
-	client := &http.Client{
-		Timeout: time.Second * 30,
-	}
+	return c.doJSON(req, endpoint)
+}
 
-	resp, err := client.Do(req)
+// Get sends a GET request to the MiniMax API
+func (c *APIClient) Get(ctx context.Context, endpoint string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.APIHost+endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %v", err)
+		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, &APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(bodyBytes),
-		}
+	return c.doJSON(req, endpoint)
+}
+
+// doJSON sends a request and decodes the JSON response, checking base_resp
+func (c *APIClient) doJSON(req *http.Request, endpoint string) (map[string]interface{}, error) {
+	resp, err := c.do(req, endpoint, time.Second*30)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -80,50 +94,60 @@ func (c *APIClient) Post(endpoint string, jsonData interface{}) (map[string]inte
 	return result, nil
 }
 
-// Get sends a GET request to the MiniMax API
-func (c *APIClient) Get(endpoint string) (map[string]interface{}, error) {
-	url := fmt.Sprintf("%s%s", c.APIHost, endpoint)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
+// do authenticates and sends a request, non-200 responses are returned as APIError.
+// The request ID of the context is forwarded so API calls can be correlated with tool calls.
+func (c *APIClient) do(req *http.Request, endpoint string, timeout time.Duration) (*http.Response, error) {
+	path, _, _ := strings.Cut(endpoint, "?")
+	ctx, span := tracing.Start(req.Context(), fmt.Sprintf("MiniMax %s %s", req.Method, path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", path),
+		))
+	defer span.End()
+	req = req.WithContext(ctx)
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	client := &http.Client{
-		Timeout: time.Second * 30,
+		Timeout:   timeout,
+		Transport: c.Transport,
 	}
 
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		metrics.ObserveAPIRequest(endpoint, 0, time.Since(start))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "request failed")
+		slog.WarnContext(ctx, "MiniMax API request failed",
+			"method", req.Method, "endpoint", endpoint, "duration", time.Since(start), "error", err)
 		return nil, fmt.Errorf("request failed: %v", err)
 	}
-	defer resp.Body.Close()
+	metrics.ObserveAPIRequest(endpoint, resp.StatusCode, time.Since(start))
+	minimaxTraceID := resp.Header.Get("Trace-Id")
+	audit.AddTraceID(ctx, minimaxTraceID)
+	span.SetAttributes(
+		attribute.Int("http.response.status_code", resp.StatusCode),
+		attribute.String("minimax.trace_id", minimaxTraceID),
+	)
+	slog.DebugContext(ctx, "MiniMax API request",
+		"method", req.Method, "endpoint", endpoint, "status", resp.StatusCode,
+		"duration", time.Since(start), "minimax_trace_id", minimaxTraceID)
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
 		return nil, &APIError{
 			StatusCode: resp.StatusCode,
 			Message:    string(bodyBytes),
 		}
 	}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("response parsing failed: %v", err)
-	}
-
-	// 检查base_resp.status_code
-	if baseResp, ok := result["base_resp"].(map[string]interface{}); ok {
-		if statusCode, ok := baseResp["status_code"].(float64); ok && statusCode != 0 {
-			statusMsg := "unknown error"
-			if msg, ok := baseResp["status_msg"].(string); ok {
-				statusMsg = msg
-			}
-			return nil, fmt.Errorf("API error: status_code=%v, message=%s", statusCode, statusMsg)
-		}
-	}
-
-	return result, nil
+	return resp, nil
 }
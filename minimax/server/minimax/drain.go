@@ -0,0 +1,109 @@
+package minimax
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+)
+
+// abortWait how long aborted handlers get to return after the grace period
+const abortWait = 5 * time.Second
+
+// Drain lets in-flight tool calls finish on shutdown while rejecting new ones
+type Drain struct {
+	// mu guards draining and inFlight, so that no call registers after Shutdown found none running
+	mu       sync.Mutex
+	draining bool
+	inFlight int
+	// idle is closed once draining started and no call is in flight
+	idle     chan struct{}
+	abortCtx context.Context
+	abort    context.CancelFunc
+}
+
+// NewDrain creates a drain accepting tool calls
+func NewDrain() *Drain {
+	d := &Drain{idle: make(chan struct{})}
+	d.abortCtx, d.abort = context.WithCancel(context.Background())
+	return d
+}
+
+// Middleware rejects tool calls once draining started and cancels running calls when the grace period ends
+func (d *Drain) Middleware() server.ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+			if !d.enter() {
+				return createTextErrorResult("Server is shutting down, retry the call later"), nil
+			}
+			defer d.leave()
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			stop := context.AfterFunc(d.abortCtx, cancel)
+			defer stop()
+
+			return next(ctx, req)
+		}
+	}
+}
+
+// enter registers a call unless draining started
+func (d *Drain) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inFlight++
+	return true
+}
+
+// leave unregisters a call and reports the drain idle when it was the last one
+func (d *Drain) leave() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	if d.draining && d.inFlight == 0 {
+		close(d.idle)
+	}
+}
+
+// Draining reports whether Shutdown was called
+func (d *Drain) Draining() bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// Shutdown stops accepting tool calls and waits for the running ones until ctx is done.
+// Calls still running then are cancelled and given a short time to return, it reports
+// whether every call finished within the grace period.
+func (d *Drain) Shutdown(ctx context.Context) bool {
+	d.mu.Lock()
+	if !d.draining {
+		d.draining = true
+		if d.inFlight == 0 {
+			close(d.idle)
+		}
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.idle:
+		return true
+	case <-ctx.Done():
+	}
+
+	d.abort()
+	select {
+	case <-d.idle:
+	case <-time.After(abortWait):
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package minimax
+
+import (
+	"context"
+	"fmt"
+)
+
+// Embedding types, "db" for stored documents and "query" for search queries
+const (
+	EmbeddingTypeDB    = "db"
+	EmbeddingTypeQuery = "query"
+)
+
+// Embeddings converts texts into vectors with the MiniMax embeddings endpoint
+func (c *APIClient) Embeddings(ctx context.Context, model string, texts []string, embeddingType string) ([][]float64, error) {
+	payload := map[string]interface{}{
+		"model": model,
+		"texts": texts,
+		"type":  embeddingType,
+	}
+
+	response, err := c.Post(ctx, "/v1/embeddings", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	rawVectors, ok := response["vectors"].([]interface{})
+	if !ok || len(rawVectors) != len(texts) {
+		return nil, fmt.Errorf("invalid API response format: expected %d vectors", len(texts))
+	}
+
+	vectors := make([][]float64, len(rawVectors))
+	for i, rawVector := range rawVectors {
+		values, ok := rawVector.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid API response format: vector %d is not an array", i)
+		}
+		vectors[i] = make([]float64, len(values))
+		for j, value := range values {
+			f, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("invalid API response format: vector %d contains a non-number", i)
+			}
+			vectors[i][j] = f
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,212 @@
+package minimax
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mcp/minimax/server/tracing"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// File purposes supported by the MiniMax files API
+const (
+	FilePurposeVoiceClone      = "voice_clone"
+	FilePurposePromptAudio     = "prompt_audio"
+	FilePurposeT2AAsyncInput   = "t2a_async_input"
+	FilePurposeT2AAsync        = "t2a_async"
+	FilePurposeVideoGeneration = "video_generation"
+)
+
+// FilePurposes lists every purpose that can be queried with list_files
+var FilePurposes = []string{
+	FilePurposeVoiceClone,
+	FilePurposePromptAudio,
+	FilePurposeT2AAsyncInput,
+	FilePurposeT2AAsync,
+	FilePurposeVideoGeneration,
+}
+
+// FileObject metadata of a file stored in the MiniMax account
+type FileObject struct {
+	FileID      int64  `json:"file_id"`
+	Bytes       int64  `json:"bytes"`
+	CreatedAt   int64  `json:"created_at"`
+	Filename    string `json:"filename"`
+	Purpose     string `json:"purpose"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// String formats the file metadata for tool results
+func (f *FileObject) String() string {
+	text := fmt.Sprintf("ID: %d, Name: %s, Purpose: %s, Size: %d bytes, Created: %s",
+		f.FileID, f.Filename, f.Purpose, f.Bytes, time.Unix(f.CreatedAt, 0).Format(time.RFC3339))
+	if f.DownloadURL != "" {
+		text += fmt.Sprintf(", Download URL: %s", f.DownloadURL)
+	}
+	return text
+}
+
+// ParseFileID converts a file id argument into the numeric id used by the API
+func ParseFileID(fileID string) (int64, error) {
+	id, err := strconv.ParseInt(fileID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid file id %q", fileID)
+	}
+	return id, nil
+}
+
+// ListFiles lists the files uploaded with the given purpose
+func (c *APIClient) ListFiles(ctx context.Context, purpose string) ([]FileObject, error) {
+	response, err := c.Get(ctx, fmt.Sprintf("/v1/files/list?purpose=%s", url.QueryEscape(purpose)))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Files []FileObject `json:"files"`
+	}
+	if err = decodeResponse(response, &result); err != nil {
+		return nil, err
+	}
+	return result.Files, nil
+}
+
+// RetrieveFile returns the metadata and download URL of a file
+func (c *APIClient) RetrieveFile(ctx context.Context, fileID int64) (*FileObject, error) {
+	response, err := c.Get(ctx, fmt.Sprintf("/v1/files/retrieve?file_id=%d", fileID))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		File *FileObject `json:"file"`
+	}
+	if err = decodeResponse(response, &result); err != nil {
+		return nil, err
+	}
+	if result.File == nil {
+		return nil, fmt.Errorf("invalid API response format: missing file object")
+	}
+	return result.File, nil
+}
+
+// DeleteFile deletes a file, the purpose must match the one it was uploaded with
+func (c *APIClient) DeleteFile(ctx context.Context, fileID int64, purpose string) error {
+	_, err := c.Post(ctx, "/v1/files/delete", map[string]interface{}{
+		"file_id": fileID,
+		"purpose": purpose,
+	})
+	return err
+}
+
+// decodeResponse converts a generic API response into a typed structure
+func decodeResponse(response map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("response encoding failed: %v", err)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("response parsing failed: %v", err)
+	}
+	return nil
+}
+
+type UploadResp struct {
+	File     FileObject `json:"file"`
+	BaseResp struct {
+		StatusCode int    `json:"status_code"`
+		StatusMsg  string `json:"status_msg"`
+	} `json:"base_resp"`
+}
+
+// UploadFile uploads a file with the given purpose to the MiniMax API
+func (c *APIClient) UploadFile(ctx context.Context, filePath, purpose string) (*FileObject, error) {
+	bodyBytes, err := c.uploadMultipart(ctx, "/v1/files/upload", filePath, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse response
+	var result = new(UploadResp)
+	if err = json.Unmarshal(bodyBytes, result); err != nil {
+		return nil, fmt.Errorf("response parsing failed: %v", err)
+	}
+
+	if result.BaseResp.StatusCode != 0 {
+		return nil, fmt.Errorf("API request error (status code: %d): %s", result.BaseResp.StatusCode, string(bodyBytes))
+	}
+
+	slog.DebugContext(ctx, "File uploaded", "file_id", result.File.FileID, "purpose", purpose)
+	return &result.File, nil
+}
+
+// uploadMultipart posts a local file with the given purpose as multipart form data and returns the raw response body
+func (c *APIClient) uploadMultipart(ctx context.Context, endpoint, filePath, purpose string) (_ []byte, err error) {
+	ctx, span := tracing.Start(ctx, "MiniMax upload", trace.WithAttributes(
+		attribute.String("minimax.file.purpose", purpose),
+		attribute.String("file.name", filepath.Base(filePath)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	// Create multipart request
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	// Add file
+	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %v", err)
+	}
+
+	if _, err = io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("failed to copy file content: %v", err)
+	}
+
+	// Add purpose field
+	if err = writer.WriteField("purpose", purpose); err != nil {
+		return nil, fmt.Errorf("failed to add purpose field: %v", err)
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close writer: %v", err)
+	}
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "POST", c.APIHost+endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	// Upload may take longer
+	resp, err := c.do(req, endpoint, time.Second*60)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	return bodyBytes, nil
+}
@@ -0,0 +1,226 @@
+package minimax
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"mcp/minimax/server/metrics"
+	"sync"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+)
+
+// ConcurrencyConfig limits on tool calls running at the same time, zero means unlimited
+type ConcurrencyConfig struct {
+	// Global limits all tool calls together
+	Global int
+	// PerTool limits individual tools by name
+	PerTool map[string]int
+	// MaxQueue is the number of calls allowed to wait for a slot, further calls are rejected as busy
+	MaxQueue int
+	// QueueTimeout rejects calls that waited longer for a slot, zero waits until the call is cancelled
+	QueueTimeout time.Duration
+}
+
+// Limiter enforces the concurrency limits, waiting calls are admitted in FIFO order
+type Limiter struct {
+	global       *fifoSemaphore
+	tools        map[string]*fifoSemaphore
+	maxQueue     int
+	queueTimeout time.Duration
+
+	mu     sync.Mutex
+	queued int
+}
+
+// NewLimiter creates a limiter, it returns nil when no limit is configured
+func NewLimiter(cfg ConcurrencyConfig) *Limiter {
+	l := &Limiter{
+		tools:        make(map[string]*fifoSemaphore),
+		maxQueue:     cfg.MaxQueue,
+		queueTimeout: cfg.QueueTimeout,
+	}
+	if cfg.Global > 0 {
+		l.global = newFIFOSemaphore(cfg.Global)
+	}
+	for tool, limit := range cfg.PerTool {
+		if limit > 0 {
+			l.tools[tool] = newFIFOSemaphore(limit)
+		}
+	}
+	if l.global == nil && len(l.tools) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Middleware runs tool calls within the limits and reports the queue time of calls that had to wait.
+// A nil limiter lets every call through.
+func (l *Limiter) Middleware() server.ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		if l == nil {
+			return next
+		}
+		return func(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+			start := time.Now()
+			release, queued, err := l.acquire(ctx, req.Name)
+			if err != nil {
+				return createTextErrorResult(err.Error()), nil
+			}
+			defer release()
+
+			waited := time.Since(start)
+			if queued {
+				metrics.QueueWait.WithLabelValues(req.Name).Observe(waited.Seconds())
+			}
+
+			result, err := next(ctx, req)
+			if queued && result != nil {
+				result.Content = append(result.Content, &protocol.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Queue time: %s", waited.Round(time.Millisecond)),
+				})
+			}
+			return result, err
+		}
+	}
+}
+
+// acquire takes the tool slot, then the global slot. It reports whether the call had to queue.
+func (l *Limiter) acquire(ctx context.Context, tool string) (func(), bool, error) {
+	sems := make([]*fifoSemaphore, 0, 2)
+	if sem, ok := l.tools[tool]; ok {
+		sems = append(sems, sem)
+	}
+	if l.global != nil {
+		sems = append(sems, l.global)
+	}
+
+	release := func(acquired []*fifoSemaphore) {
+		for i := len(acquired) - 1; i >= 0; i-- {
+			acquired[i].release()
+		}
+	}
+
+	// Fast path, every slot is free and nobody is waiting
+	acquired := make([]*fifoSemaphore, 0, len(sems))
+	for _, sem := range sems {
+		if !sem.tryAcquire() {
+			break
+		}
+		acquired = append(acquired, sem)
+	}
+	if len(acquired) == len(sems) {
+		return func() { release(acquired) }, false, nil
+	}
+
+	if !l.enqueue() {
+		release(acquired)
+		metrics.BusyRejections.WithLabelValues(tool).Inc()
+		return nil, false, fmt.Errorf("Server busy: %d tool calls are already queued, retry later", l.maxQueue)
+	}
+	defer l.dequeue()
+
+	if l.queueTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.queueTimeout)
+		defer cancel()
+	}
+	for _, sem := range sems[len(acquired):] {
+		if err := sem.acquire(ctx); err != nil {
+			release(acquired)
+			metrics.BusyRejections.WithLabelValues(tool).Inc()
+			return nil, true, fmt.Errorf("Server busy: no slot for %s became free while queued: %v", tool, err)
+		}
+		acquired = append(acquired, sem)
+	}
+	return func() { release(acquired) }, true, nil
+}
+
+func (l *Limiter) enqueue() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.queued >= l.maxQueue {
+		return false
+	}
+	l.queued++
+	metrics.QueueDepth.Set(float64(l.queued))
+	return true
+}
+
+func (l *Limiter) dequeue() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.queued--
+	metrics.QueueDepth.Set(float64(l.queued))
+}
+
+// fifoSemaphore a counting semaphore granting slots to waiters in arrival order
+type fifoSemaphore struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	waiters list.List // of chan struct{}
+}
+
+func newFIFOSemaphore(limit int) *fifoSemaphore {
+	return &fifoSemaphore{limit: limit}
+}
+
+// tryAcquire takes a slot without waiting, it never overtakes queued callers
+func (s *fifoSemaphore) tryAcquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active < s.limit && s.waiters.Len() == 0 {
+		s.active++
+		return true
+	}
+	return false
+}
+
+func (s *fifoSemaphore) acquire(ctx context.Context) error {
+	s.mu.Lock()
+	if s.active < s.limit && s.waiters.Len() == 0 {
+		s.active++
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// The slot was handed over while giving up, pass it on
+			s.mu.Unlock()
+			s.release()
+		default:
+			s.waiters.Remove(elem)
+			s.mu.Unlock()
+		}
+		return ctx.Err()
+	}
+}
+
+// release hands the slot to the oldest waiter or frees it
+func (s *fifoSemaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if front := s.waiters.Front(); front != nil {
+		s.waiters.Remove(front)
+		close(front.Value.(chan struct{}))
+		return
+	}
+	s.active--
+}
@@ -0,0 +1,42 @@
+package minimax
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// isRemoteOrDataURI reports whether ref is already in a form the MiniMax API accepts directly
+func isRemoteOrDataURI(ref string) bool {
+	return strings.HasPrefix(ref, "http://") ||
+		strings.HasPrefix(ref, "https://") ||
+		strings.HasPrefix(ref, "data:")
+}
+
+// resolveImageInput converts an image reference into a value accepted by the MiniMax API.
+// URLs and data URIs are passed through, local files are read and encoded as a data URI
+// using the MIME type sniffed from the file content.
+func resolveImageInput(ref string) (string, error) {
+	if isRemoteOrDataURI(ref) {
+		return ref, nil
+	}
+
+	if _, err := os.Stat(ref); os.IsNotExist(err) {
+		return "", fmt.Errorf("image file does not exist: %s", ref)
+	}
+
+	imgData, err := os.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image file: %v", err)
+	}
+
+	mimeType := http.DetectContentType(imgData)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("unsupported image type %s: %s", mimeType, ref)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(imgData)
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, encoded), nil
+}
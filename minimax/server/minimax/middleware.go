@@ -0,0 +1,86 @@
+package minimax
+
+import (
+	"context"
+	"log/slog"
+	"mcp/logging"
+	"mcp/minimax/server/metrics"
+	"mcp/minimax/server/tracing"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestLogMiddleware assigns every tool call a request ID and logs its outcome.
+// The request ID is carried in the context so API calls and logs of the same call can be correlated.
+func RequestLogMiddleware() server.ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+			ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+			sessionID, _ := server.GetSessionIDFromCtx(ctx)
+			logger := slog.With("tool", req.Name, "session_id", sessionID)
+
+			logger.DebugContext(ctx, "Tool call started")
+			start := time.Now()
+			result, err := next(ctx, req)
+			duration := time.Since(start)
+
+			if err != nil {
+				logger.ErrorContext(ctx, "Tool call failed", "duration", duration, "error", err)
+				return result, err
+			}
+			logger.InfoContext(ctx, "Tool call finished", "duration", duration, "is_error", result != nil && result.IsError)
+			return result, nil
+		}
+	}
+}
+
+// TracingMiddleware starts a span for every tool call, continuing the trace the client
+// sent in the request _meta when present. It must run before the other middlewares.
+func TracingMiddleware() server.ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+			sessionID, _ := server.GetSessionIDFromCtx(ctx)
+			ctx, span := tracing.Start(tracing.ExtractMeta(ctx, req.Meta), "tools/call "+req.Name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("mcp.tool.name", req.Name),
+					attribute.String("mcp.session.id", sessionID),
+				))
+			defer span.End()
+
+			result, err := next(ctx, req)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			} else if result != nil && result.IsError {
+				span.SetStatus(codes.Error, "tool returned an error result")
+			}
+			return result, err
+		}
+	}
+}
+
+// MetricsMiddleware records call counts, outcomes and durations per tool, including rejected calls and queue time
+func MetricsMiddleware() server.ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+			start := time.Now()
+			result, err := next(ctx, req)
+			metrics.ToolCallDuration.WithLabelValues(req.Name).Observe(time.Since(start).Seconds())
+
+			status := metrics.StatusOK
+			if err != nil {
+				status = metrics.StatusFailed
+			} else if result != nil && result.IsError {
+				status = metrics.StatusError
+			}
+			metrics.ToolCalls.WithLabelValues(req.Name, status).Inc()
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package minimax
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"mcp/minimax/server/define"
+	"mcp/minimax/server/provider"
+)
+
+// Provider implements the media provider interfaces with the MiniMax API
+type Provider struct {
+	Client *APIClient
+}
+
+// NewProvider creates a MiniMax provider using client
+func NewProvider(client *APIClient) *Provider {
+	return &Provider{Client: client}
+}
+
+// Synthesize converts text to speech with /v1/t2a_v2
+func (p *Provider) Synthesize(ctx context.Context, req provider.SpeechRequest) (*provider.Audio, error) {
+	if req.Model == "" {
+		req.Model = define.DefaultT2AModel
+	}
+	if req.VoiceID == "" {
+		req.VoiceID = define.DefaultVoiceID
+	}
+
+	payload := map[string]interface{}{
+		"model": req.Model,
+		"text":  req.Text,
+		"voice_setting": map[string]interface{}{
+			"voice_id": req.VoiceID,
+			"speed":    req.Speed,
+			"vol":      req.Vol,
+			"pitch":    req.Pitch,
+			"emotion":  req.Emotion,
+		},
+		"audio_setting": map[string]interface{}{
+			"sample_rate": req.SampleRate,
+			"bitrate":     req.Bitrate,
+			"format":      req.Format,
+			"channel":     req.Channel,
+		},
+		"language_boost": req.LanguageBoost,
+	}
+	if req.URLOutput {
+		payload["output_format"] = "url"
+	}
+
+	response, err := p.Client.Post(ctx, "/v1/t2a_v2", payload)
+	if err != nil {
+		return nil, fmt.Errorf("API call failed: %v", err)
+	}
+
+	data, ok := response["data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid API response format: missing data field")
+	}
+	audioData, ok := data["audio"].(string)
+	if !ok || audioData == "" {
+		return nil, fmt.Errorf("invalid API response format: unable to get audio data")
+	}
+
+	if req.URLOutput {
+		return &provider.Audio{URL: audioData, Voice: req.VoiceID}, nil
+	}
+	audioBytes, err := hex.DecodeString(audioData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio data: %v", err)
+	}
+	return &provider.Audio{Data: audioBytes, Voice: req.VoiceID}, nil
+}
+
+// GenerateImages generates images with /v1/image_generation
+func (p *Provider) GenerateImages(ctx context.Context, req provider.ImageRequest) (*provider.Images, error) {
+	if req.Model == "" {
+		req.Model = define.DefaultT2IModel
+	}
+	responseFormat := "url"
+	if req.Base64 {
+		responseFormat = "base64"
+	}
+
+	payload := map[string]interface{}{
+		"model":            req.Model,
+		"prompt":           req.Prompt,
+		"n":                req.N,
+		"prompt_optimizer": req.PromptOptimizer,
+		"response_format":  responseFormat,
+	}
+	// aspect_ratio takes priority over a custom size, as the tool describes
+	if req.AspectRatio != "" {
+		payload["aspect_ratio"] = req.AspectRatio
+	} else if req.Width != 0 {
+		payload["width"] = req.Width
+		payload["height"] = req.Height
+	}
+	if req.Seed != 0 {
+		payload["seed"] = req.Seed
+	}
+	if req.Watermark {
+		payload["aigc_watermark"] = true
+	}
+	// Subject reference keeps the character consistent across generated images
+	if req.SubjectReference != "" {
+		payload["subject_reference"] = []map[string]interface{}{
+			{
+				"type":       "character",
+				"image_file": req.SubjectReference,
+			},
+		}
+	}
+
+	response, err := p.Client.Post(ctx, "/v1/image_generation", payload)
+	if err != nil {
+		return nil, fmt.Errorf("image generation API call failed: %v", err)
+	}
+
+	data, ok := response["data"].(map[string]interface{})
+	if !ok {
+		slog.WarnContext(ctx, "Image generation response has no data field", "response", response)
+		return nil, fmt.Errorf("invalid API response format: missing data field")
+	}
+
+	images := &provider.Images{}
+	if req.Base64 {
+		encoded, _ := data["image_base64"].([]interface{})
+		for _, item := range encoded {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid image data format")
+			}
+			imageBytes, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode base64 image: %v", err)
+			}
+			images.Data = append(images.Data, imageBytes)
+		}
+		return images, nil
+	}
+	urls, _ := data["image_urls"].([]interface{})
+	for _, item := range urls {
+		if url, ok := item.(string); ok {
+			images.URLs = append(images.URLs, url)
+		}
+	}
+	return images, nil
+}
+
+// SubmitVideo starts a task with /v1/video_generation
+func (p *Provider) SubmitVideo(ctx context.Context, req provider.VideoRequest) (string, error) {
+	payload := map[string]interface{}{
+		"model":  req.Model,
+		"prompt": req.Prompt,
+	}
+	if req.Duration != 0 {
+		payload["duration"] = req.Duration
+	}
+	if req.Resolution != "" {
+		payload["resolution"] = req.Resolution
+	}
+	if req.FirstFrameImage != "" {
+		payload["first_frame_image"] = req.FirstFrameImage
+	}
+	if req.LastFrameImage != "" {
+		payload["last_frame_image"] = req.LastFrameImage
+	}
+	if req.SubjectReference != "" {
+		payload["subject_reference"] = []map[string]interface{}{
+			{
+				"type":  "character",
+				"image": []string{req.SubjectReference},
+			},
+		}
+	}
+
+	response, err := p.Client.Post(ctx, "/v1/video_generation", payload)
+	if err != nil {
+		return "", fmt.Errorf("video generation API call failed: %v", err)
+	}
+
+	taskID, ok := response["task_id"].(string)
+	if !ok || taskID == "" {
+		return "", fmt.Errorf("unable to get task_id from response")
+	}
+	return taskID, nil
+}
+
+// VideoStatus queries a task and retrieves the download URL of its video once it succeeded
+func (p *Provider) VideoStatus(ctx context.Context, taskID string) (*provider.VideoStatus, error) {
+	response, err := p.Client.Get(ctx, fmt.Sprintf("/v1/query/video_generation?task_id=%s", taskID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query video generation status: %v", err)
+	}
+
+	status, ok := response["status"].(string)
+	if !ok {
+		return nil, fmt.Errorf("unable to get status from response")
+	}
+
+	switch status {
+	case "Fail":
+		return &provider.VideoStatus{State: provider.VideoFailed, Status: status}, nil
+	case "Success":
+	default:
+		return &provider.VideoStatus{State: provider.VideoPending, Status: status}, nil
+	}
+
+	fileID, ok := response["file_id"].(string)
+	if !ok || fileID == "" {
+		return nil, fmt.Errorf("unable to get file_id from success response, task ID: %s", taskID)
+	}
+	videoFileID, err := ParseFileID(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse file_id from response, task ID: %s", taskID)
+	}
+
+	fileObj, err := p.Client.RetrieveFile(ctx, videoFileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video file information: %v", err)
+	}
+	if fileObj.DownloadURL == "" {
+		return nil, fmt.Errorf("unable to get download URL, file ID: %s", fileID)
+	}
+	return &provider.VideoStatus{State: provider.VideoSucceeded, Status: status, URL: fileObj.DownloadURL}, nil
+}
+
+// CloneVoice uploads the audio sample and clones the voice with /v1/voice_clone
+func (p *Provider) CloneVoice(ctx context.Context, req provider.VoiceCloneRequest) (*provider.ClonedVoice, error) {
+	uploaded, err := p.Client.UploadFile(ctx, req.File, FilePurposeVoiceClone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file: %v", err)
+	}
+
+	payload := map[string]interface{}{
+		"file_id":  uploaded.FileID,
+		"voice_id": req.VoiceID,
+	}
+	if req.Text != "" {
+		payload["text"] = req.Text
+		payload["model"] = define.DefaultVCModel
+	}
+
+	response, err := p.Client.Post(ctx, "/v1/voice_clone", payload)
+	if err != nil {
+		return nil, fmt.Errorf("voice cloning API call failed: %v", err)
+	}
+
+	demoAudio, _ := response["demo_audio"].(string)
+	return &provider.ClonedVoice{VoiceID: req.VoiceID, DemoAudioURL: demoAudio}, nil
+}
@@ -0,0 +1,100 @@
+package minimax
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mcp/minimax/server/identity"
+	"mcp/minimax/server/metrics"
+	"mcp/minimax/server/quota"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+)
+
+// quotaChargeKey the context key of the flag keeping the usage of a call charged
+type quotaChargeKey struct{}
+
+// QuotaMiddleware charges tool calls to the quotas of their session and client.
+// Calls over a quota are rejected with the quota status so agents can back off,
+// usage of calls that end in an error is given back unless the handler kept it
+// with keepQuotaCharge. A nil manager lets every call through.
+func QuotaMiddleware(quotas *quota.Manager) server.ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		if quotas == nil {
+			return next
+		}
+		return func(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+			sessionID, _ := server.GetSessionIDFromCtx(ctx)
+			reservation, err := quotas.Reserve(sessionID, identity.Client(ctx), estimateUsage(req))
+			if err != nil {
+				var exceeded *quota.ExceededError
+				if errors.As(err, &exceeded) {
+					metrics.BudgetRejections.WithLabelValues(exceeded.Scope).Inc()
+				}
+				return createTextErrorResult(err.Error()), nil
+			}
+
+			ctx, keep := withQuotaCharge(ctx)
+			result, err := next(ctx, req)
+			if !keep.Load() && (err != nil || (result != nil && result.IsError)) {
+				quotas.Release(reservation)
+			}
+			return result, err
+		}
+	}
+}
+
+// withQuotaCharge returns the flag keeping the usage of the call in ctx charged, adding it to ctx
+// unless an outer middleware already did, so that the audit log and the quotas agree on what is charged
+func withQuotaCharge(ctx context.Context) (context.Context, *atomic.Bool) {
+	if keep, ok := ctx.Value(quotaChargeKey{}).(*atomic.Bool); ok {
+		return ctx, keep
+	}
+	keep := &atomic.Bool{}
+	return context.WithValue(ctx, quotaChargeKey{}, keep), keep
+}
+
+// keepQuotaCharge keeps the usage of the call in ctx charged even if it ends in an error,
+// e.g. once MiniMax accepted a video task that runs and is billed regardless. It is a no-op
+// outside the quota and audit middlewares.
+func keepQuotaCharge(ctx context.Context) {
+	if keep, ok := ctx.Value(quotaChargeKey{}).(*atomic.Bool); ok {
+		keep.Store(true)
+	}
+}
+
+// estimateUsage returns the resources a tool call consumes, from its arguments
+func estimateUsage(req *protocol.CallToolRequest) quota.Usage {
+	usage := quota.Usage{Calls: 1}
+
+	var args struct {
+		Text         string        `json:"text"`
+		N            int           `json:"n"`
+		Lyrics       string        `json:"lyrics"`
+		SystemPrompt string        `json:"system_prompt"`
+		Messages     []ChatMessage `json:"messages"`
+		Prompt       string        `json:"prompt"`
+	}
+	_ = json.Unmarshal(req.RawArguments, &args)
+
+	switch req.Name {
+	case "text_to_audio", "voice_clone":
+		usage.Characters = utf8.RuneCountInString(args.Text)
+	case "generate_music":
+		usage.Characters = utf8.RuneCountInString(args.Lyrics)
+	case "chat_completion":
+		// The input characters, the completion is not known in advance
+		usage.Characters = utf8.RuneCountInString(args.SystemPrompt) + utf8.RuneCountInString(args.Prompt)
+		for _, message := range args.Messages {
+			usage.Characters += utf8.RuneCountInString(message.Content)
+		}
+	case "text_to_image":
+		usage.Images = max(args.N, 1)
+	case "generate_video":
+		usage.Videos = 1
+	}
+	return usage
+}
@@ -1,17 +1,19 @@
 package minimax
 
 import (
-	"bytes"
-	"encoding/base64"
+	"context"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
-	"io/ioutil"
-	"log"
+	"log/slog"
+	"mcp/minimax/server/audit"
 	"mcp/minimax/server/define"
+	"mcp/minimax/server/metrics"
+	"mcp/minimax/server/provider"
 	"mcp/minimax/server/storage"
-	"mime/multipart"
+	"mcp/minimax/server/tracing"
+	"mcp/minimax/server/vectorstore"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -19,18 +21,68 @@ import (
 	"time"
 
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ProgressNotifier sends progress notifications for the call of a context, implemented by the SDK server
+type ProgressNotifier interface {
+	SendProgressNotification(ctx context.Context, notify *protocol.ProgressNotification) error
+}
+
 // MCPServer MCP server instance
 type MCPServer struct {
 	Client       *APIClient
 	ResourceMode string
+	Download     storage.DownloadOptions
+	Index        *vectorstore.Index
+	Tracker      *Tracker
+	PendingTasks *PendingTasks
+	Limiter      *Limiter
+	// Drain tells a shutdown, which keeps interrupted video tasks for recovery, from a cancelled call
+	Drain *Drain
+	// Notifier sends progress notifications to the client of a call, e.g. the deltas of a streamed chat completion
+	Notifier ProgressNotifier
+
+	// Media providers of the tools, MiniMax through Client when nil
+	Speech     provider.SpeechProvider
+	Image      provider.ImageProvider
+	Video      provider.VideoProvider
+	VoiceClone provider.VoiceCloneProvider
+}
+
+func (s *MCPServer) speechProvider() provider.SpeechProvider {
+	if s.Speech != nil {
+		return s.Speech
+	}
+	return NewProvider(s.Client)
+}
+
+func (s *MCPServer) imageProvider() provider.ImageProvider {
+	if s.Image != nil {
+		return s.Image
+	}
+	return NewProvider(s.Client)
+}
+
+func (s *MCPServer) videoProvider() provider.VideoProvider {
+	if s.Video != nil {
+		return s.Video
+	}
+	return NewProvider(s.Client)
+}
+
+func (s *MCPServer) voiceCloneProvider() provider.VoiceCloneProvider {
+	if s.VoiceClone != nil {
+		return s.VoiceClone
+	}
+	return NewProvider(s.Client)
 }
 
 // API method implementations
 
 // HandleTextToAudio processes text-to-speech requests, save To Local
-func (s *MCPServer) HandleTextToAudio(req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+func (s *MCPServer) HandleTextToAudio(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
 	var params TextToAudioRequest
 	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
 		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
@@ -41,13 +93,7 @@ func (s *MCPServer) HandleTextToAudio(req *protocol.CallToolRequest) (*protocol.
 		return createTextErrorResult("The text parameter must be provided"), nil
 	}
 
-	// Fill optional parameters with default values
-	if params.VoiceID == "" {
-		params.VoiceID = define.DefaultVoiceID
-	}
-	if params.Model == "" {
-		params.Model = define.DefaultT2AModel
-	}
+	// Fill optional parameters with default values, the provider chooses the model and voice
 	if params.Speed == 0 {
 		params.Speed = define.DefaultSpeed
 	}
@@ -74,57 +120,29 @@ func (s *MCPServer) HandleTextToAudio(req *protocol.CallToolRequest) (*protocol.
 		params.LanguageBoost = define.DefaultLanguageBoost
 	}
 
-	// Build request payload
-	payload := map[string]interface{}{
-		"model": params.Model,
-		"text":  params.Text,
-		"voice_setting": map[string]interface{}{
-			"voice_id": params.VoiceID,
-			"speed":    params.Speed,
-			"vol":      params.Vol,
-			"pitch":    params.Pitch,
-			"emotion":  params.Emotion,
-		},
-		"audio_setting": map[string]interface{}{
-			"sample_rate": params.SampleRate,
-			"bitrate":     params.Bitrate,
-			"format":      params.Format,
-			"channel":     params.Channel,
-		},
-		"language_boost": params.LanguageBoost,
-	}
-
-	// If resource mode is URL, add output format
-	if s.ResourceMode == define.ResourceModeURL {
-		payload["output_format"] = "url"
-	}
-
-	// Call API
-	response, err := s.Client.Post("/v1/t2a_v2", payload)
+	audio, err := s.speechProvider().Synthesize(ctx, provider.SpeechRequest{
+		Text:          params.Text,
+		Model:         params.Model,
+		VoiceID:       params.VoiceID,
+		Speed:         params.Speed,
+		Vol:           params.Vol,
+		Pitch:         params.Pitch,
+		Emotion:       params.Emotion,
+		SampleRate:    params.SampleRate,
+		Bitrate:       params.Bitrate,
+		Channel:       params.Channel,
+		Format:        params.Format,
+		LanguageBoost: params.LanguageBoost,
+		URLOutput:     s.ResourceMode == define.ResourceModeURL,
+	})
 	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("API call failed: %v", err)), nil
-	}
-
-	// Process response
-	data, ok := response["data"].(map[string]interface{})
-	if !ok {
-		return createTextErrorResult("Invalid API response format: missing data field"), nil
+		return createTextErrorResult(err.Error()), nil
 	}
 
-	audioData, ok := data["audio"].(string)
-	if !ok || audioData == "" {
-		return createTextErrorResult("Invalid API response format: unable to get audio data"), nil
-	}
-
-	// Return different results based on resource mode
-	if s.ResourceMode == define.ResourceModeURL {
-		return createTextResult(fmt.Sprintf("Success. Audio URL: %s", audioData)), nil
-	}
-
-	// Convert hex string to binary data
-	audioBytes, err := hex.DecodeString(audioData)
-	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to decode audio data: %v", err)), nil
+	// Providers that cannot return a URL deliver the audio, which is then saved like in local mode
+	if audio.URL != "" {
+		audit.AddOutput(ctx, audio.URL)
+		return createTextResult(fmt.Sprintf("Success. Audio URL: %s", audio.URL)), nil
 	}
 
 	// Save audio file
@@ -137,15 +155,16 @@ func (s *MCPServer) HandleTextToAudio(req *protocol.CallToolRequest) (*protocol.
 	}
 
 	// Write file
-	if err = os.WriteFile(outputFileName, audioBytes, 0644); err != nil {
+	if err = os.WriteFile(outputFileName, audio.Data, 0644); err != nil {
 		return createTextErrorResult(fmt.Sprintf("Failed to save audio file: %v", err)), nil
 	}
+	audit.AddOutput(ctx, outputFileName)
 
-	return createTextResult(fmt.Sprintf("Success. File saved as: %s. Voice used: %s", outputFileName, params.VoiceID)), nil
+	return createTextResult(fmt.Sprintf("Success. File saved as: %s. Voice used: %s", outputFileName, audio.Voice)), nil
 }
 
 // HandleListVoices processes list voices requests
-func (s *MCPServer) HandleListVoices(req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+func (s *MCPServer) HandleListVoices(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
 	var params ListVoicesRequest
 	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
 		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
@@ -161,7 +180,7 @@ func (s *MCPServer) HandleListVoices(req *protocol.CallToolRequest) (*protocol.C
 		"voice_type": params.VoiceType,
 	}
 
-	response, err := s.Client.Post("/v1/get_voice", payload)
+	response, err := s.Client.Post(ctx, "/v1/get_voice", payload)
 	if err != nil {
 		return createTextErrorResult(fmt.Sprintf("API call failed: %v", err)), nil
 	}
@@ -213,7 +232,7 @@ func (s *MCPServer) HandleListVoices(req *protocol.CallToolRequest) (*protocol.C
 }
 
 // HandleVoiceClone processes voice cloning requests
-func (s *MCPServer) HandleVoiceClone(req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+func (s *MCPServer) HandleVoiceClone(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
 	var params VoiceCloneRequest
 	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
 		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
@@ -233,105 +252,193 @@ func (s *MCPServer) HandleVoiceClone(req *protocol.CallToolRequest) (*protocol.C
 	//	params.Model = define.DefaultVCModel
 	//}
 
-	var fileID int64
-	var err error
+	// Step 1: Get a local copy of the audio sample
+	localFile, cleanup, err := s.localAudioFile(ctx, params.File, params.IsURL, "voice_clone_*.mp3")
+	defer cleanup()
+	if err != nil {
+		return createTextErrorResult(fmt.Sprintf("Failed to prepare file: %v", err)), nil
+	}
 
-	// Step 1: Upload file
-	if params.IsURL {
-		// Download file from URL
-		resp, err := http.Get(params.File)
-		if err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to download file: %v", err)), nil
-		}
-		defer resp.Body.Close()
+	// Step 2: Clone voice
+	cloned, err := s.voiceCloneProvider().CloneVoice(ctx, provider.VoiceCloneRequest{
+		VoiceID: params.VoiceID,
+		File:    localFile,
+		Text:    params.Text,
+	})
+	if err != nil {
+		return createTextErrorResult(err.Error()), nil
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			return createTextErrorResult(fmt.Sprintf("Failed to download file, status code: %d", resp.StatusCode)), nil
-		}
+	demoAudio := cloned.DemoAudioURL
+	if demoAudio == "" {
+		// There may be no demo audio, just return success message
+		return createTextResult(fmt.Sprintf("Voice cloning successful. Voice ID: %s", params.VoiceID)), nil
+	}
 
-		// Create temporary file
-		tempFile, err := os.CreateTemp("", "voice_clone_*.mp3")
-		if err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to create temporary file: %v", err)), nil
+	// If in URL mode, return URL directly
+	if s.ResourceMode == define.ResourceModeURL {
+		audit.AddOutput(ctx, demoAudio)
+		return createTextResult(fmt.Sprintf("Success. Demo audio URL: %s", demoAudio)), nil
+	}
+
+	// Download and save demo audio
+	outputPath := storage.BuildOutputPath()
+	outputFileName := storage.BuildOutputFile("voice_clone", params.Text, outputPath, "wav")
+
+	download, err := storage.Download(ctx, demoAudio, outputFileName, s.Download)
+	if err != nil {
+		return createTextErrorResult(fmt.Sprintf("Failed to download demo audio: %v", err)), nil
+	}
+	audit.AddOutput(ctx, download.Path)
+
+	return createTextResult(fmt.Sprintf("Voice cloning successful: Voice ID: %s, demo audio saved as: %s (%d bytes, sha256 %s)", params.VoiceID, download.Path, download.Bytes, download.SHA256)), nil
+}
+
+// localAudioFile returns a local path for an audio input, downloading it to a temporary file when it is a URL.
+// The returned cleanup function removes any temporary file and must always be called.
+func (s *MCPServer) localAudioFile(ctx context.Context, file string, isURL bool, pattern string) (string, func(), error) {
+	if !isURL {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return "", func() {}, fmt.Errorf("local file does not exist: %s", file)
 		}
-		defer os.Remove(tempFile.Name())
-		defer tempFile.Close()
+		return file, func() {}, nil
+	}
 
-		// Write content to temporary file
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to read response: %v", err)), nil
+	tempFile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", func() {}, fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	tempFile.Close()
+	cleanup := func() { os.Remove(tempFile.Name()) }
+
+	if _, err = storage.Download(ctx, file, tempFile.Name(), s.Download); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("failed to download file: %v", err)
+	}
+	return tempFile.Name(), cleanup, nil
+}
+
+// MusicUploadResp music reference upload response
+type MusicUploadResp struct {
+	VoiceID        string `json:"voice_id"`
+	InstrumentalID string `json:"instrumental_id"`
+	BaseResp       struct {
+		StatusCode int    `json:"status_code"`
+		StatusMsg  string `json:"status_msg"`
+	} `json:"base_resp"`
+}
+
+// HandleGenerateMusic processes music generation requests, save To Local
+func (s *MCPServer) HandleGenerateMusic(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	var params GenerateMusicRequest
+	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
+	}
+
+	// Check required parameters
+	if params.Lyrics == "" {
+		return createTextErrorResult("The lyrics parameter must be provided"), nil
+	}
+
+	// Fill optional parameters with default values, reference audio needs the music-01 model
+	if params.Model == "" {
+		if params.ReferenceAudio != "" {
+			params.Model = define.DefaultMusicRefModel
+		} else {
+			params.Model = define.DefaultMusicModel
 		}
+	}
+	if params.Model != define.DefaultMusicRefModel && params.Prompt == "" {
+		return createTextErrorResult(fmt.Sprintf("The prompt parameter must be provided for model %s", params.Model)), nil
+	}
+	if params.Model != define.DefaultMusicRefModel && params.ReferenceAudio != "" {
+		return createTextErrorResult(fmt.Sprintf("Reference audio is only supported by model %s", define.DefaultMusicRefModel)), nil
+	}
+	if params.SampleRate == 0 {
+		params.SampleRate = define.DefaultMusicSampleRate
+	}
+	if params.Bitrate == 0 {
+		params.Bitrate = define.DefaultMusicBitrate
+	}
+	if params.Format == "" {
+		params.Format = define.DefaultFormat
+	}
+
+	// Build request payload
+	payload := map[string]interface{}{
+		"model":  params.Model,
+		"lyrics": params.Lyrics,
+		"audio_setting": map[string]interface{}{
+			"sample_rate": params.SampleRate,
+			"bitrate":     params.Bitrate,
+			"format":      params.Format,
+		},
+	}
+	if params.Prompt != "" {
+		payload["prompt"] = params.Prompt
+	}
 
-		if _, err := tempFile.Write(bodyBytes); err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to write to temporary file: %v", err)), nil
+	// Upload reference audio and use the returned ids as voice and instrumental references
+	if params.ReferenceAudio != "" {
+		if params.ReferencePurpose == "" {
+			params.ReferencePurpose = "song"
 		}
-		tempFile.Close()
 
-		// Upload temporary file
-		fileID, err = s.uploadFile(tempFile.Name())
+		localFile, cleanup, err := s.localAudioFile(ctx, params.ReferenceAudio, params.IsURL, "music_reference_*.mp3")
+		defer cleanup()
 		if err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to upload file: %v", err)), nil
-		}
-	} else {
-		// Upload local file
-		if _, err := os.Stat(params.File); os.IsNotExist(err) {
-			return createTextErrorResult(fmt.Sprintf("Local file does not exist: %s", params.File)), nil
+			return createTextErrorResult(fmt.Sprintf("Failed to prepare reference audio: %v", err)), nil
 		}
 
-		fileID, err = s.uploadFile(params.File)
+		reference, err := s.uploadMusicReference(ctx, localFile, params.ReferencePurpose)
 		if err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to upload file: %v", err)), nil
+			return createTextErrorResult(fmt.Sprintf("Failed to upload reference audio: %v", err)), nil
+		}
+		if reference.VoiceID != "" {
+			payload["refer_voice"] = reference.VoiceID
+		}
+		if reference.InstrumentalID != "" {
+			payload["refer_instrumental"] = reference.InstrumentalID
 		}
 	}
 
-	// Step 2: Clone voice
-	payload := map[string]interface{}{
-		"file_id":  fileID,
-		"voice_id": params.VoiceID,
-	}
-
-	if params.Text != "" {
-		payload["text"] = params.Text
-		payload["model"] = define.DefaultVCModel
+	// If resource mode is URL, add output format
+	if s.ResourceMode == define.ResourceModeURL {
+		payload["output_format"] = "url"
 	}
 
-	response, err := s.Client.Post("/v1/voice_clone", payload)
+	// Call API
+	response, err := s.Client.Post(ctx, "/v1/music_generation", payload)
 	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Voice cloning API call failed: %v", err)), nil
+		return createTextErrorResult(fmt.Sprintf("Music generation API call failed: %v", err)), nil
 	}
 
-	demoAudio, ok := response["demo_audio"].(string)
-	if !ok || demoAudio == "" {
-		// There may be no demo audio, just return success message
-		return createTextResult(fmt.Sprintf("Voice cloning successful. Voice ID: %s", params.VoiceID)), nil
-	}
-
-	// If in URL mode, return URL directly
-	if s.ResourceMode == define.ResourceModeURL {
-		return createTextResult(fmt.Sprintf("Success. Demo audio URL: %s", demoAudio)), nil
+	// Process response
+	data, ok := response["data"].(map[string]interface{})
+	if !ok {
+		return createTextErrorResult("Invalid API response format: missing data field"), nil
 	}
 
-	// Download demo audio
-	resp, err := http.Get(demoAudio)
-	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to download demo audio: %v", err)), nil
+	audioData, ok := data["audio"].(string)
+	if !ok || audioData == "" {
+		return createTextErrorResult("Invalid API response format: unable to get audio data"), nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return createTextErrorResult(fmt.Sprintf("Failed to download demo audio, status code: %d", resp.StatusCode)), nil
+	// Return different results based on resource mode
+	if s.ResourceMode == define.ResourceModeURL {
+		audit.AddOutput(ctx, audioData)
+		return createTextResult(fmt.Sprintf("Success. Music URL: %s", audioData)), nil
 	}
 
-	// Read audio content
-	audioBytes, err := io.ReadAll(resp.Body)
+	// Convert hex string to binary data
+	audioBytes, err := hex.DecodeString(audioData)
 	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to read demo audio: %v", err)), nil
+		return createTextErrorResult(fmt.Sprintf("Failed to decode audio data: %v", err)), nil
 	}
 
-	// Save demo audio file
+	// Save music file
 	outputPath := storage.BuildOutputPath()
-	outputFileName := storage.BuildOutputFile("voice_clone", params.Text, outputPath, "wav")
+	outputFileName := storage.BuildOutputFile("music", params.Lyrics, outputPath, params.Format)
 
 	// Ensure directory exists
 	if err = os.MkdirAll(filepath.Dir(outputFileName), 0755); err != nil {
@@ -340,101 +447,393 @@ func (s *MCPServer) HandleVoiceClone(req *protocol.CallToolRequest) (*protocol.C
 
 	// Write file
 	if err = os.WriteFile(outputFileName, audioBytes, 0644); err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to save audio file: %v", err)), nil
+		return createTextErrorResult(fmt.Sprintf("Failed to save music file: %v", err)), nil
 	}
+	audit.AddOutput(ctx, outputFileName)
 
-	return createTextResult(fmt.Sprintf("Voice cloning successful: Voice ID: %s, demo audio saved as: %s", params.VoiceID, outputFileName)), nil
+	return createTextResult(fmt.Sprintf("Success. File saved as: %s. Model used: %s", outputFileName, params.Model)), nil
 }
 
-type UploadResp struct {
-	File struct {
-		FileId    int64  `json:"file_id"`
-		Bytes     int    `json:"bytes"`
-		CreatedAt int    `json:"created_at"`
-		Filename  string `json:"filename"`
-		Purpose   string `json:"purpose"`
-	} `json:"file"`
-	BaseResp struct {
-		StatusCode int    `json:"status_code"`
-		StatusMsg  string `json:"status_msg"`
-	} `json:"base_resp"`
+// uploadMusicReference uploads a reference track for music-01 generation
+func (s *MCPServer) uploadMusicReference(ctx context.Context, filePath, purpose string) (*MusicUploadResp, error) {
+	bodyBytes, err := s.Client.uploadMultipart(ctx, "/v1/music_upload", filePath, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = new(MusicUploadResp)
+	if err = json.Unmarshal(bodyBytes, result); err != nil {
+		return nil, fmt.Errorf("response parsing failed: %v", err)
+	}
+
+	if result.BaseResp.StatusCode != 0 {
+		return nil, fmt.Errorf("API request error (status code: %d): %s", result.BaseResp.StatusCode, result.BaseResp.StatusMsg)
+	}
+
+	return result, nil
+}
+
+// HandleChatCompletion processes chat completion requests
+func (s *MCPServer) HandleChatCompletion(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	var params ChatCompletionToolRequest
+	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
+	}
+
+	// Check required parameters
+	if params.Prompt == "" && len(params.Messages) == 0 {
+		return createTextErrorResult("The prompt or messages parameter must be provided"), nil
+	}
+	if params.Temperature != nil && (*params.Temperature <= 0 || *params.Temperature > 1) {
+		return createTextErrorResult("The temperature must be in range (0, 1]"), nil
+	}
+
+	// Fill optional parameters with default values
+	if params.Model == "" {
+		params.Model = define.DefaultChatModel
+	}
+
+	// Build messages: system prompt, history, then the new user prompt
+	messages := make([]ChatMessage, 0, len(params.Messages)+2)
+	if params.SystemPrompt != "" {
+		messages = append(messages, ChatMessage{Role: "system", Content: params.SystemPrompt})
+	}
+	messages = append(messages, params.Messages...)
+	if params.Prompt != "" {
+		messages = append(messages, ChatMessage{Role: "user", Content: params.Prompt})
+	}
+
+	chatReq := &ChatCompletionRequest{
+		Model:       params.Model,
+		Messages:    messages,
+		Temperature: params.Temperature,
+		MaxTokens:   params.MaxTokens,
+	}
+
+	var (
+		response *ChatCompletionResponse
+		err      error
+	)
+	if params.Stream {
+		response, err = s.Client.ChatCompletionStream(ctx, chatReq, s.progressSender(ctx, req))
+	} else {
+		response, err = s.Client.ChatCompletion(ctx, chatReq)
+	}
+	if err != nil {
+		return createTextErrorResult(fmt.Sprintf("Chat completion API call failed: %v", err)), nil
+	}
+
+	content := response.Content()
+	if content == "" {
+		return createTextErrorResult("Invalid API response format: empty completion"), nil
+	}
+
+	return createTextResult(content), nil
+}
+
+// progressSender returns a callback sending every delta as a progress notification, the message is the delta
+// and the progress the number of deltas sent. It is nil when the client did not ask for progress.
+func (s *MCPServer) progressSender(ctx context.Context, req *protocol.CallToolRequest) func(string) {
+	if s.Notifier == nil || req.Meta[protocol.ProgressTokenKey] == nil {
+		return nil
+	}
+	var sent float64
+	return func(delta string) {
+		sent++
+		if err := s.Notifier.SendProgressNotification(ctx, protocol.NewProgressNotification(sent, 0, delta)); err != nil {
+			slog.Debug("Failed to send progress notification", "error", err)
+		}
+	}
+}
+
+// HandleEmbedText processes text embedding requests
+func (s *MCPServer) HandleEmbedText(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	var params EmbedTextRequest
+	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
+	}
+
+	// Check required parameters
+	if len(params.Texts) == 0 {
+		return createTextErrorResult("The texts parameter must be provided"), nil
+	}
+
+	// Fill optional parameters with default values
+	if params.Model == "" {
+		params.Model = define.DefaultEmbeddingModel
+	}
+	if params.Type == "" {
+		params.Type = EmbeddingTypeDB
+	}
+
+	vectors, err := s.embedBatches(ctx, params.Model, params.Texts, params.Type)
+	if err != nil {
+		return createTextErrorResult(fmt.Sprintf("Embeddings API call failed: %v", err)), nil
+	}
+
+	result, err := json.Marshal(map[string]interface{}{
+		"model":   params.Model,
+		"type":    params.Type,
+		"vectors": vectors,
+	})
+	if err != nil {
+		return createTextErrorResult(fmt.Sprintf("Failed to encode vectors: %v", err)), nil
+	}
+
+	return createTextResult(string(result)), nil
 }
 
-// uploadFile uploads a file to the MiniMax API
-func (s *MCPServer) uploadFile(filePath string) (int64, error) {
-	file, err := os.Open(filePath)
+// HandleIndexDocuments embeds documents and local text files into the vector index
+func (s *MCPServer) HandleIndexDocuments(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	var params IndexDocumentsRequest
+	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
+	}
+
+	if s.Index == nil {
+		return createTextErrorResult("The vector index is not enabled"), nil
+	}
+
+	// Check required parameters
+	if len(params.Documents) == 0 && len(params.Files) == 0 {
+		return createTextErrorResult("The documents or files parameter must be provided"), nil
+	}
+
+	docs := make([]vectorstore.Document, 0, len(params.Documents))
+	for _, doc := range params.Documents {
+		if doc.Text == "" {
+			return createTextErrorResult("Every document must have text"), nil
+		}
+		id := doc.ID
+		if id == "" {
+			sum := sha256.Sum256([]byte(doc.Text))
+			id = hex.EncodeToString(sum[:8])
+		}
+		docs = append(docs, vectorstore.Document{ID: id, Text: doc.Text, Source: doc.Source})
+	}
+
+	// Local files are split into chunks identified by path and chunk number
+	for _, file := range params.Files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return createTextErrorResult(fmt.Sprintf("Failed to read file: %v", err)), nil
+		}
+		for i, chunk := range vectorstore.Chunk(string(content), params.ChunkSize) {
+			docs = append(docs, vectorstore.Document{ID: fmt.Sprintf("%s#%d", file, i), Text: chunk, Source: file})
+		}
+	}
+
+	texts := make([]string, len(docs))
+	for i := range docs {
+		texts[i] = docs[i].Text
+	}
+
+	vectors, err := s.embedBatches(ctx, define.DefaultEmbeddingModel, texts, EmbeddingTypeDB)
 	if err != nil {
-		return 0, fmt.Errorf("failed to open file: %v", err)
+		return createTextErrorResult(fmt.Sprintf("Embeddings API call failed: %v", err)), nil
+	}
+	for i := range docs {
+		docs[i].Vector = vectors[i]
+	}
+
+	// Chunks of an earlier version of a file are replaced, not only those with the same number
+	if err = s.Index.Replace(params.Files, docs); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Failed to update index: %v", err)), nil
+	}
+
+	return createTextResult(fmt.Sprintf("Success. Indexed %d documents, the index at %s now holds %d documents", len(docs), s.Index.Path(), s.Index.Len())), nil
+}
+
+// HandleSearchDocuments searches the vector index for documents similar to a query
+func (s *MCPServer) HandleSearchDocuments(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	var params SearchDocumentsRequest
+	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
 	}
-	defer file.Close()
 
-	// Create multipart request
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+	if s.Index == nil {
+		return createTextErrorResult("The vector index is not enabled"), nil
+	}
 
-	// Add file
-	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+	// Check required parameters
+	if params.Query == "" {
+		return createTextErrorResult("The query parameter must be provided"), nil
+	}
+
+	// Fill optional parameters with default values
+	if params.TopK <= 0 {
+		params.TopK = define.DefaultSearchTopK
+	}
+
+	vectors, err := s.Client.Embeddings(ctx, define.DefaultEmbeddingModel, []string{params.Query}, EmbeddingTypeQuery)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create form file: %v", err)
+		return createTextErrorResult(fmt.Sprintf("Embeddings API call failed: %v", err)), nil
 	}
 
-	if _, err = io.Copy(part, file); err != nil {
-		return 0, fmt.Errorf("failed to copy file content: %v", err)
+	matches := s.Index.Search(vectors[0], params.TopK)
+	if len(matches) == 0 {
+		return createTextResult("No matching documents"), nil
 	}
 
-	// Add purpose field
-	if err = writer.WriteField("purpose", "voice_clone"); err != nil {
-		return 0, fmt.Errorf("failed to add purpose field: %v", err)
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Top %d matching documents:\n\n", len(matches)))
+	for i, match := range matches {
+		resultText.WriteString(fmt.Sprintf("%d. ID: %s, Score: %.4f", i+1, match.ID, match.Score))
+		if match.Source != "" {
+			resultText.WriteString(fmt.Sprintf(", Source: %s", match.Source))
+		}
+		resultText.WriteString(fmt.Sprintf("\n%s\n\n", match.Text))
 	}
 
-	if err = writer.Close(); err != nil {
-		return 0, fmt.Errorf("failed to close writer: %v", err)
+	return createTextResult(resultText.String()), nil
+}
+
+// embedBatches embeds texts in batches to stay within the API request limits
+func (s *MCPServer) embedBatches(ctx context.Context, model string, texts []string, embeddingType string) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += define.DefaultEmbeddingBatchSize {
+		end := min(start+define.DefaultEmbeddingBatchSize, len(texts))
+		batch, err := s.Client.Embeddings(ctx, model, texts[start:end], embeddingType)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// HandleListFiles processes list files requests
+func (s *MCPServer) HandleListFiles(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	var params ListFilesRequest
+	if len(req.RawArguments) > 0 {
+		if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
+			return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
+		}
+	}
+
+	// The API lists one purpose at a time, query all of them by default
+	purposes := FilePurposes
+	if params.Purpose != "" {
+		purposes = []string{params.Purpose}
+	}
+
+	var resultText strings.Builder
+	total := 0
+	for _, purpose := range purposes {
+		files, err := s.Client.ListFiles(ctx, purpose)
+		if err != nil {
+			return createTextErrorResult(fmt.Sprintf("Failed to list %s files: %v", purpose, err)), nil
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		resultText.WriteString(fmt.Sprintf("%s files:\n", purpose))
+		for i := range files {
+			resultText.WriteString(fmt.Sprintf("%d. %s\n", i+1, files[i].String()))
+		}
+		resultText.WriteString("\n")
+		total += len(files)
+	}
+
+	if total == 0 {
+		return createTextResult("No files"), nil
+	}
+	return createTextResult(fmt.Sprintf("Found %d files:\n\n%s", total, resultText.String())), nil
+}
+
+// HandleGetFile processes get file requests
+func (s *MCPServer) HandleGetFile(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	var params FileIDRequest
+	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
+	}
+
+	fileID, err := ParseFileID(params.FileID)
+	if err != nil {
+		return createTextErrorResult(err.Error()), nil
 	}
 
-	// Create request
-	url := fmt.Sprintf("%s/v1/files/upload", s.Client.APIHost)
-	req, err := http.NewRequest("POST", url, body)
+	file, err := s.Client.RetrieveFile(ctx, fileID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %v", err)
+		return createTextErrorResult(fmt.Sprintf("Failed to get file information: %v", err)), nil
 	}
 
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.Client.APIKey))
+	return createTextResult(file.String()), nil
+}
 
-	// Send request
-	client := &http.Client{
-		Timeout: time.Second * 60, // Upload may take longer
+// HandleDownloadFile processes download file requests, save To Local
+func (s *MCPServer) HandleDownloadFile(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	var params FileIDRequest
+	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
 	}
 
-	resp, err := client.Do(req)
+	fileID, err := ParseFileID(params.FileID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to send request: %v", err)
+		return createTextErrorResult(err.Error()), nil
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
+	file, err := s.Client.RetrieveFile(ctx, fileID)
+	if err != nil {
+		return createTextErrorResult(fmt.Sprintf("Failed to get file information: %v", err)), nil
+	}
+	if file.DownloadURL == "" {
+		return createTextErrorResult(fmt.Sprintf("Unable to get download URL, file ID: %d", fileID)), nil
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("API request error (status code: %d): %s", resp.StatusCode, string(bodyBytes))
+	// If in URL mode, return URL directly
+	if s.ResourceMode == define.ResourceModeURL {
+		audit.AddOutput(ctx, file.DownloadURL)
+		return createTextResult(fmt.Sprintf("Success. %s", file.String())), nil
 	}
 
-	log.Printf("%s", bodyBytes)
+	ext := strings.TrimPrefix(filepath.Ext(file.Filename), ".")
+	if ext == "" {
+		ext = "bin"
+	}
+	outputPath := storage.BuildOutputPath()
+	outputFileName := storage.BuildOutputFile("file", strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)), outputPath, ext)
 
-	// Parse response
-	var result = new(UploadResp)
-	if err = json.Unmarshal(bodyBytes, result); err != nil {
-		return 0, fmt.Errorf("response parsing failed: %v", err)
+	download, err := storage.Download(ctx, file.DownloadURL, outputFileName, s.Download)
+	if err != nil {
+		return createTextErrorResult(fmt.Sprintf("Failed to download file: %v", err)), nil
 	}
+	audit.AddOutput(ctx, download.Path)
 
-	if result.BaseResp.StatusCode != 0 {
-		return 0, fmt.Errorf("API request error (status code: %d): %s", result.BaseResp.StatusCode, string(bodyBytes))
+	return createTextResult(fmt.Sprintf("Success. File saved as: %s (%d bytes, sha256 %s). %s", download.Path, download.Bytes, download.SHA256, file.String())), nil
+}
+
+// HandleDeleteFile processes delete file requests
+func (s *MCPServer) HandleDeleteFile(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	var params DeleteFileRequest
+	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
+	}
+
+	fileID, err := ParseFileID(params.FileID)
+	if err != nil {
+		return createTextErrorResult(err.Error()), nil
+	}
+
+	// The API requires the purpose, look it up when it is not provided
+	if params.Purpose == "" {
+		file, err := s.Client.RetrieveFile(ctx, fileID)
+		if err != nil {
+			return createTextErrorResult(fmt.Sprintf("Failed to get file information: %v", err)), nil
+		}
+		params.Purpose = file.Purpose
 	}
 
-	return result.File.FileId, nil
+	if err = s.Client.DeleteFile(ctx, fileID, params.Purpose); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Failed to delete file: %v", err)), nil
+	}
+
+	return createTextResult(fmt.Sprintf("Success. File %d deleted", fileID)), nil
 }
 
 // HandleGenerateVideo processes video generation requests
-func (s *MCPServer) HandleGenerateVideo(req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+func (s *MCPServer) HandleGenerateVideo(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
 	var params GenerateVideoRequest
 	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
 		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
@@ -445,146 +844,164 @@ func (s *MCPServer) HandleGenerateVideo(req *protocol.CallToolRequest) (*protoco
 		return createTextErrorResult("The prompt parameter must be provided"), nil
 	}
 
-	// Fill optional parameters with default values
+	// Fill optional parameters with default values, picking the model family from the inputs
 	if params.Model == "" {
-		params.Model = define.DefaultT2VModel
+		switch {
+		case params.SubjectReference != "":
+			params.Model = define.DefaultS2VModel
+		case params.FirstFrameImage != "":
+			params.Model = define.DefaultI2VModel
+		default:
+			params.Model = define.DefaultT2VModel
+		}
 	}
 
-	// Build request payload
-	payload := map[string]interface{}{
-		"model":  params.Model,
-		"prompt": params.Prompt,
+	if err := validateVideoRequest(&params); err != nil {
+		return createTextErrorResult(fmt.Sprintf("Invalid video parameters: %v", err)), nil
+	}
+
+	videoReq := provider.VideoRequest{
+		Model:      params.Model,
+		Prompt:     params.Prompt,
+		Duration:   params.Duration,
+		Resolution: params.Resolution,
 	}
 
 	// If a first frame image is provided
 	if params.FirstFrameImage != "" {
-		// Validate image
-		if !strings.HasPrefix(params.FirstFrameImage, "http://") &&
-			!strings.HasPrefix(params.FirstFrameImage, "https://") &&
-			!strings.HasPrefix(params.FirstFrameImage, "data:") {
-			// Local image, convert to dataURL
-			if _, err := os.Stat(params.FirstFrameImage); os.IsNotExist(err) {
-				return createTextErrorResult(fmt.Sprintf("First frame image file does not exist: %s", params.FirstFrameImage)), nil
-			}
+		firstFrame, err := resolveValidatedImage(params.FirstFrameImage)
+		if err != nil {
+			return createTextErrorResult(fmt.Sprintf("Invalid first frame image: %v", err)), nil
+		}
+		videoReq.FirstFrameImage = firstFrame
+	}
 
-			// Read image file
-			imgData, err := ioutil.ReadFile(params.FirstFrameImage)
-			if err != nil {
-				return createTextErrorResult(fmt.Sprintf("Failed to read image file: %v", err)), nil
-			}
+	// If a last frame image is provided
+	if params.LastFrameImage != "" {
+		lastFrame, err := resolveValidatedImage(params.LastFrameImage)
+		if err != nil {
+			return createTextErrorResult(fmt.Sprintf("Invalid last frame image: %v", err)), nil
+		}
+		videoReq.LastFrameImage = lastFrame
+	}
 
-			// Convert to base64
-			encoded := base64.StdEncoding.EncodeToString(imgData)
-			payload["first_frame_image"] = fmt.Sprintf("data:image/jpeg;base64,%s", encoded)
-		} else {
-			payload["first_frame_image"] = params.FirstFrameImage
+	// If a subject reference image is provided
+	if params.SubjectReference != "" {
+		subject, err := resolveValidatedImage(params.SubjectReference)
+		if err != nil {
+			return createTextErrorResult(fmt.Sprintf("Invalid subject reference image: %v", err)), nil
 		}
+		videoReq.SubjectReference = subject
 	}
 
-	// Call API to submit video generation task
-	response, err := s.Client.Post("/v1/video_generation", payload)
+	// Submit the video generation task
+	taskID, err := s.videoProvider().SubmitVideo(ctx, videoReq)
 	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Video generation API call failed: %v", err)), nil
+		return createTextErrorResult(err.Error()), nil
 	}
 
-	// Get task ID
-	taskID, ok := response["task_id"].(string)
-	if !ok || taskID == "" {
-		return createTextErrorResult("Unable to get task_id from response"), nil
+	s.Tracker.SetTaskID(ctx, taskID)
+	audit.SetTaskID(ctx, taskID)
+	keepQuotaCharge(ctx)
+	s.PendingTasks.Add(PendingTask{
+		TaskID:      taskID,
+		Model:       params.Model,
+		Prompt:      params.Prompt,
+		SubmittedAt: time.Now(),
+	})
+
+	text, err := s.completeVideoTask(ctx, taskID)
+	if err != nil {
+		if ctx.Err() != nil && s.Drain.Draining() && s.PendingTasks != nil {
+			// The task keeps running at MiniMax, it is recovered on the next start
+			return createTextErrorResult(fmt.Sprintf("Video generation interrupted, task ID %s was saved and will be recovered when the server restarts", taskID)), nil
+		}
+		s.PendingTasks.Remove(taskID)
+		if ctx.Err() != nil {
+			return createTextErrorResult(fmt.Sprintf("Video generation cancelled, task ID %s is no longer polled", taskID)), nil
+		}
+		return createTextErrorResult(err.Error()), nil
 	}
+	s.PendingTasks.Remove(taskID)
 
+	return createTextResult(text), nil
+}
+
+// completeVideoTask polls a video generation task until it finishes, then returns the video URL
+// in url resource mode or downloads the video. Polling stops when ctx is cancelled.
+func (s *MCPServer) completeVideoTask(ctx context.Context, taskID string) (string, error) {
 	// Poll task completion status
-	var fileID string
+	pollStart := time.Now()
+	metrics.VideoJobsInProgress.Inc()
+	pollStatus := "timeout"
+	defer func() {
+		metrics.VideoJobsInProgress.Dec()
+		metrics.VideoPollDuration.WithLabelValues(pollStatus).Observe(time.Since(pollStart).Seconds())
+	}()
+
+	var downloadURL string
 	maxRetries := 30    // Up to 10 minutes (30 * 20 seconds)
 	retryInterval := 20 // seconds
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		// Check task status
-		statusResponse, err := s.Client.Get(fmt.Sprintf("/v1/query/video_generation?task_id=%s", taskID))
-		if err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to query video generation status: %v", err)), nil
+		pollCtx, span := tracing.Start(ctx, "video poll", trace.WithAttributes(
+			attribute.String("minimax.task_id", taskID),
+			attribute.Int("minimax.poll.attempt", attempt),
+		))
+		status, err := s.videoProvider().VideoStatus(pollCtx, taskID)
+		if err == nil {
+			span.SetAttributes(attribute.String("minimax.task_status", status.Status))
 		}
-
-		status, ok := statusResponse["status"].(string)
-		if !ok {
-			return createTextErrorResult("Unable to get status from response"), nil
+		tracing.End(span, err)
+		if err != nil {
+			pollStatus = "error"
+			return "", err
 		}
 
-		if status == "Fail" {
-			return createTextErrorResult(fmt.Sprintf("Video generation failed, task ID: %s", taskID)), nil
-		} else if status == "Success" {
-			// Get file ID
-			fileID, ok = statusResponse["file_id"].(string)
-			if !ok || fileID == "" {
-				return createTextErrorResult(fmt.Sprintf("Unable to get file_id from success response, task ID: %s", taskID)), nil
-			}
+		if status.State == provider.VideoFailed {
+			pollStatus = "fail"
+			return "", fmt.Errorf("Video generation failed, task ID: %s", taskID)
+		} else if status.State == provider.VideoSucceeded {
+			pollStatus = "success"
+			downloadURL = status.URL
 			break
 		}
 
 		// Still processing, wait and retry
-		time.Sleep(time.Duration(retryInterval) * time.Second)
-	}
-
-	if fileID == "" {
-		return createTextErrorResult(fmt.Sprintf("Timeout getting file_id, task ID: %s", taskID)), nil
-	}
-
-	// Get video download URL
-	fileResponse, err := s.Client.Get(fmt.Sprintf("/v1/files/retrieve?file_id=%s", fileID))
-	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to get video file information: %v", err)), nil
-	}
-
-	fileObj, ok := fileResponse["file"].(map[string]interface{})
-	if !ok {
-		return createTextErrorResult("Unable to get file object from response"), nil
+		select {
+		case <-ctx.Done():
+			pollStatus = "cancelled"
+			return "", fmt.Errorf("Video generation polling stopped, task ID: %s: %v", taskID, ctx.Err())
+		case <-time.After(time.Duration(retryInterval) * time.Second):
+		}
 	}
 
-	downloadURL, ok := fileObj["download_url"].(string)
-	if !ok || downloadURL == "" {
-		return createTextErrorResult(fmt.Sprintf("Unable to get download URL, file ID: %s", fileID)), nil
+	if downloadURL == "" {
+		return "", fmt.Errorf("Timeout getting the video, task ID: %s", taskID)
 	}
 
 	// If in URL mode, return URL directly
 	if s.ResourceMode == define.ResourceModeURL {
-		return createTextResult(fmt.Sprintf("Success. Video URL: %s", downloadURL)), nil
+		audit.AddOutput(ctx, downloadURL)
+		return fmt.Sprintf("Success. Video URL: %s", downloadURL), nil
 	}
 
 	// Download and save video
-	resp, err := http.Get(downloadURL)
-	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to download video: %v", err)), nil
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return createTextErrorResult(fmt.Sprintf("Failed to download video, status code: %d", resp.StatusCode)), nil
-	}
-
-	videoBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to read video data: %v", err)), nil
-	}
-
-	// Save video file
 	outputPath := storage.BuildOutputPath()
 	outputFileName := storage.BuildOutputFile("video", taskID, outputPath, "mp4")
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(outputFileName), 0755); err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to create output directory: %v", err)), nil
-	}
-
-	// Write file
-	if err := os.WriteFile(outputFileName, videoBytes, 0644); err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to save video file: %v", err)), nil
+	download, err := storage.Download(ctx, downloadURL, outputFileName, s.Download)
+	if err != nil {
+		return "", fmt.Errorf("Failed to download video: %v", err)
 	}
+	audit.AddOutput(ctx, download.Path)
 
-	return createTextResult(fmt.Sprintf("Success. Video saved as: %s", outputFileName)), nil
+	return fmt.Sprintf("Success. Video saved as: %s (%d bytes, sha256 %s)", download.Path, download.Bytes, download.SHA256), nil
 }
 
 // HandleTextToImage processes text-to-image requests
-func (s *MCPServer) HandleTextToImage(req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+func (s *MCPServer) HandleTextToImage(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
 	var params TextToImageRequest
 	if err := protocol.VerifyAndUnmarshal(req.RawArguments, &params); err != nil {
 		return createTextErrorResult(fmt.Sprintf("Parameter parsing failed: %v", err)), nil
@@ -595,11 +1012,8 @@ func (s *MCPServer) HandleTextToImage(req *protocol.CallToolRequest) (*protocol.
 		return createTextErrorResult("The prompt parameter must be provided"), nil
 	}
 
-	// Fill optional parameters with default values
-	if params.Model == "" {
-		params.Model = define.DefaultT2IModel
-	}
-	if params.AspectRatio == "" {
+	// Fill optional parameters with default values, the provider chooses the model
+	if params.AspectRatio == "" && params.Width == 0 && params.Height == 0 {
 		params.AspectRatio = "1:1"
 	}
 	if params.N == 0 {
@@ -615,120 +1029,120 @@ func (s *MCPServer) HandleTextToImage(req *protocol.CallToolRequest) (*protocol.
 		promptOptimizer = params.PromptOptimizer
 	}
 
-	// Build request payload
-	payload := map[string]interface{}{
-		"model":            params.Model,
-		"prompt":           params.Prompt,
-		"aspect_ratio":     params.AspectRatio,
-		"n":                params.N,
-		"prompt_optimizer": promptOptimizer,
-		"response_format":  params.ResponseFormat,
+	// Validate custom resolution, both sides are required and must be multiples of 8
+	if params.Width != 0 || params.Height != 0 {
+		if params.Width == 0 || params.Height == 0 {
+			return createTextErrorResult("The width and height parameters must be provided together"), nil
+		}
+		if !validImageSide(params.Width) || !validImageSide(params.Height) {
+			return createTextErrorResult("The width and height must be in range [512, 2048] and divisible by 8"), nil
+		}
 	}
 
-	// Call API
-	response, err := s.Client.Post("/v1/image_generation", payload)
-	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Image generation API call failed: %v", err)), nil
+	imageReq := provider.ImageRequest{
+		Prompt:          params.Prompt,
+		Model:           params.Model,
+		AspectRatio:     params.AspectRatio,
+		Width:           params.Width,
+		Height:          params.Height,
+		N:               params.N,
+		Seed:            params.Seed,
+		PromptOptimizer: promptOptimizer,
+		Watermark:       params.Watermark,
+		Base64:          params.ResponseFormat == "base64",
 	}
 
-	// Process response
-	data, ok := response["data"].(map[string]interface{})
-	if !ok {
-		log.Printf("resp : %v", response)
-		return createTextErrorResult("Invalid API response format: missing data field"), nil
+	// Subject reference keeps the character consistent across generated images
+	if params.SubjectReference != "" {
+		imageFile, err := resolveValidatedImage(params.SubjectReference)
+		if err != nil {
+			return createTextErrorResult(fmt.Sprintf("Invalid subject reference image: %v", err)), nil
+		}
+		imageReq.SubjectReference = imageFile
 	}
 
-	switch params.ResponseFormat {
-	case "base64":
-		return textToImageResultWithImageContent(data)
-	default:
-		imageURLs, ok := data["image_urls"].([]interface{})
-		if !ok || len(imageURLs) == 0 {
+	images, err := s.imageProvider().GenerateImages(ctx, imageReq)
+	if err != nil {
+		return createTextErrorResult(err.Error()), nil
+	}
+
+	switch {
+	case params.ResponseFormat == "base64":
+		if len(images.Data) == 0 {
 			return createTextErrorResult("No images generated"), nil
 		}
-		_, _ = textToImageResultWithTextContent(storage.BuildOutputPath(), params.Prompt, imageURLs)
-		return createTextResult(fmt.Sprintf("Success. Image URLs: %v", imageURLs)), nil
+		return createImageResult(images.Data[0]), nil
+	case len(images.URLs) > 0:
+		for _, imageURL := range images.URLs {
+			audit.AddOutput(ctx, imageURL)
+		}
+		_, _ = textToImageResultWithTextContent(ctx, storage.BuildOutputPath(), params.Prompt, images.URLs, s.Download)
+		return createTextResult(fmt.Sprintf("Success. Image URLs: %v", images.URLs)), nil
+	case len(images.Data) > 0:
+		// Providers that only return image data get their images saved locally
+		return saveImages(ctx, storage.BuildOutputPath(), params.Prompt, images.Data), nil
+	default:
+		return createTextErrorResult("No images generated"), nil
 	}
 }
 
-func textToImageResultWithTextContent(outputPath, prompt string, imageURLs []interface{}) (*protocol.CallToolResult, error) {
+// validImageSide checks a custom image side length against the image-01 limits
+func validImageSide(side int) bool {
+	return side >= 512 && side <= 2048 && side%8 == 0
+}
+
+func textToImageResultWithTextContent(ctx context.Context, outputPath, prompt string, imageURLs []string, opts storage.DownloadOptions) (*protocol.CallToolResult, error) {
 
 	// Download and save images
 	var outputFileNames []string
 
-	for i, urlInterface := range imageURLs {
-		imageURL, ok := urlInterface.(string)
-		if !ok {
-			continue
-		}
-
-		// Create output filename
-		truncatedPrompt := prompt
-		if len(truncatedPrompt) > 50 {
-			truncatedPrompt = truncatedPrompt[:50]
-		}
-
-		outputFileName := storage.BuildOutputFile("t2i", fmt.Sprintf("%d_%s", i, truncatedPrompt), outputPath, "jpeg")
-
-		// Ensure directory exists
-		if err := os.MkdirAll(filepath.Dir(outputFileName), 0755); err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to create output directory: %v", err)), nil
-		}
+	for i, imageURL := range imageURLs {
+		outputFileName := imageOutputFile(outputPath, prompt, i, "jpeg")
 
 		// Download image
-		resp, err := http.Get(imageURL)
-		if err != nil {
+		if _, err := storage.Download(ctx, imageURL, outputFileName, opts); err != nil {
 			return createTextErrorResult(fmt.Sprintf("Failed to download image: %v", err)), nil
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return createTextErrorResult(fmt.Sprintf("Failed to download image, status code: %d", resp.StatusCode)), nil
-		}
-
-		// Read image data
-		imageBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to read image data: %v", err)), nil
-		}
-
-		// Write file
-		if err = os.WriteFile(outputFileName, imageBytes, 0644); err != nil {
-			return createTextErrorResult(fmt.Sprintf("Failed to save image file: %v", err)), nil
-		}
 
+		audit.AddOutput(ctx, outputFileName)
 		outputFileNames = append(outputFileNames, outputFileName)
 	}
 
 	return createTextResult(fmt.Sprintf("Success. Images saved as: %v", outputFileNames)), nil
 }
 
-func textToImageResultWithImageContent(data map[string]interface{}) (*protocol.CallToolResult, error) {
-	imageBase64, ok := data["image_base64"].([]interface{})
-	if !ok || len(imageBase64) == 0 {
-		return createTextErrorResult("No images generated"), nil
-	}
-
-	// 获取第一张图片的base64字符串
-	base64Str, ok := imageBase64[0].(string)
-	if !ok {
-		return createTextErrorResult("Invalid image data format"), nil
+// saveImages writes generated image data to the output directory
+func saveImages(ctx context.Context, outputPath, prompt string, images [][]byte) *protocol.CallToolResult {
+	var outputFileNames []string
+	for i, image := range images {
+		_, ext, _ := strings.Cut(http.DetectContentType(image), "/")
+		outputFileName := imageOutputFile(outputPath, prompt, i, ext)
+		if err := os.MkdirAll(filepath.Dir(outputFileName), 0755); err != nil {
+			return createTextErrorResult(fmt.Sprintf("Failed to create output directory: %v", err))
+		}
+		if err := os.WriteFile(outputFileName, image, 0644); err != nil {
+			return createTextErrorResult(fmt.Sprintf("Failed to save image: %v", err))
+		}
+		audit.AddOutput(ctx, outputFileName)
+		outputFileNames = append(outputFileNames, outputFileName)
 	}
+	return createTextResult(fmt.Sprintf("Success. Images saved as: %v", outputFileNames))
+}
 
-	// 解码base64字符串为二进制数据
-	imageBytes, err := base64.StdEncoding.DecodeString(base64Str)
-	if err != nil {
-		return createTextErrorResult(fmt.Sprintf("Failed to decode base64 image: %v", err)), nil
+// imageOutputFile returns the output file of the i-th image generated for prompt
+func imageOutputFile(outputPath, prompt string, i int, ext string) string {
+	truncatedPrompt := prompt
+	if len(truncatedPrompt) > 50 {
+		truncatedPrompt = truncatedPrompt[:50]
 	}
-
-	return createImageResult(imageBytes), nil
+	return storage.BuildOutputFile("t2i", fmt.Sprintf("%d_%s", i, truncatedPrompt), outputPath, ext)
 }
 
 // Create text result
 func createTextResult(text string) *protocol.CallToolResult {
 	return &protocol.CallToolResult{
 		Content: []protocol.Content{
-			protocol.TextContent{
+			&protocol.TextContent{
 				Type: "text",
 				Text: text,
 			},
@@ -741,7 +1155,7 @@ func createTextErrorResult(text string) *protocol.CallToolResult {
 	return &protocol.CallToolResult{
 		IsError: true,
 		Content: []protocol.Content{
-			protocol.TextContent{
+			&protocol.TextContent{
 				Type: "text",
 				Text: text,
 			},
@@ -753,7 +1167,7 @@ func createTextErrorResult(text string) *protocol.CallToolResult {
 func createImageResult(data []byte) *protocol.CallToolResult {
 	return &protocol.CallToolResult{
 		Content: []protocol.Content{
-			protocol.ImageContent{
+			&protocol.ImageContent{
 				Type:     "image",
 				Data:     data,
 				MimeType: "image/jpeg",
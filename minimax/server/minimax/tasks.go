@@ -0,0 +1,146 @@
+package minimax
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PendingTask a MiniMax task submitted but not yet delivered to the caller
+type PendingTask struct {
+	TaskID      string    `json:"task_id"`
+	Model       string    `json:"model,omitempty"`
+	Prompt      string    `json:"prompt,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// PendingTasks a file backed list of pending video tasks.
+// Tasks are written when submitted and removed once delivered, so tasks interrupted
+// by a shutdown or a crash are still on disk on the next start.
+// All methods are no-ops on a nil store.
+type PendingTasks struct {
+	path  string
+	mu    sync.Mutex
+	tasks map[string]PendingTask
+}
+
+// OpenPendingTasks loads the pending tasks stored at path, a missing file is an empty list
+func OpenPendingTasks(path string) (*PendingTasks, error) {
+	store := &PendingTasks{
+		path:  path,
+		tasks: make(map[string]PendingTask),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending tasks: %v", err)
+	}
+
+	var tasks []PendingTask
+	if err = json.Unmarshal(data, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to parse pending tasks %s: %v", path, err)
+	}
+	for _, task := range tasks {
+		store.tasks[task.TaskID] = task
+	}
+	return store, nil
+}
+
+// Add records a submitted task
+func (p *PendingTasks) Add(task PendingTask) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tasks[task.TaskID] = task
+	p.save()
+}
+
+// Remove forgets a delivered or failed task
+func (p *PendingTasks) Remove(taskID string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.tasks[taskID]; !ok {
+		return
+	}
+	delete(p.tasks, taskID)
+	p.save()
+}
+
+// List returns the pending tasks, oldest first
+func (p *PendingTasks) List() []PendingTask {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tasks := make([]PendingTask, 0, len(p.tasks))
+	for _, task := range p.tasks {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].SubmittedAt.Before(tasks[j].SubmittedAt) })
+	return tasks
+}
+
+// save rewrites the file atomically, the caller holds the lock.
+// Failures are logged rather than returned, a lost entry must not fail the tool call.
+func (p *PendingTasks) save() {
+	tasks := make([]PendingTask, 0, len(p.tasks))
+	for _, task := range p.tasks {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].TaskID < tasks[j].TaskID })
+
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(p.path), 0755)
+	}
+	tmp := p.path + ".tmp"
+	if err == nil {
+		err = os.WriteFile(tmp, data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, p.path)
+	}
+	if err != nil {
+		slog.Error("Failed to persist pending tasks", "path", p.path, "error", err)
+	}
+}
+
+// RecoverVideoTasks resumes the video tasks left pending by a previous run in the background.
+// Finished videos are delivered as usual, downloaded or logged with their URL, since the
+// original caller is gone.
+func (s *MCPServer) RecoverVideoTasks(ctx context.Context) {
+	for _, task := range s.PendingTasks.List() {
+		slog.Info("Recovering pending video task", "task_id", task.TaskID, "submitted_at", task.SubmittedAt)
+		go func(task PendingTask) {
+			text, err := s.completeVideoTask(ctx, task.TaskID)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Pending video task failed", "task_id", task.TaskID, "error", err)
+					s.PendingTasks.Remove(task.TaskID)
+				}
+				return
+			}
+			slog.Info("Pending video task recovered", "task_id", task.TaskID, "result", text)
+			s.PendingTasks.Remove(task.TaskID)
+		}(task)
+	}
+}
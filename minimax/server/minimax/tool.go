@@ -1,10 +1,12 @@
 package minimax
 
 import (
-	"context"
+	"fmt"
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
 	"github.com/ThinkInAIXYZ/go-mcp/server"
-	"log"
+	"log/slog"
+	"slices"
+	"strings"
 )
 
 // TextToAudioRequest 文本转语音请求
@@ -37,88 +39,345 @@ type VoiceCloneRequest struct {
 	//Model   string `json:"model" description:"The model to use. Values range [\"speech-02-hd\"、\"speech-02-turbo\"、\"speech-01-hd\"、\"speech-01-turbo\"、\"speech-01-240228\"、\"speech-01-turbo-240228\"]"`
 }
 
+// GenerateMusicRequest 音乐生成请求
+type GenerateMusicRequest struct {
+	Model            string `json:"model,omitempty" description:"The model to use. Values range [\"music-1.5\", \"music-01\"]. Defaults to \"music-1.5\", or \"music-01\" when reference_audio is provided."`
+	Lyrics           string `json:"lyrics" description:"The lyrics of the song. Use \\n to separate lines, and structure tags such as [Intro], [Verse], [Chorus], [Bridge] and [Outro] to arrange the song."`
+	Prompt           string `json:"prompt,omitempty" description:"The style of the music, e.g. genre, mood and scene. Required by \"music-1.5\"."`
+	ReferenceAudio   string `json:"reference_audio,omitempty" description:"The path or URL of a reference audio file whose vocal and accompaniment style is imitated. Only supported by \"music-01\"."`
+	IsURL            bool   `json:"is_url,omitempty" description:"Whether the reference_audio is a URL. Defaults to False."`
+	ReferencePurpose string `json:"reference_purpose,omitempty" description:"How the reference audio is used. Values range [\"song\", \"voice\", \"instrumental\"], \"song\" references both vocal and accompaniment. Defaults to \"song\"." enum:"song,voice,instrumental"`
+	SampleRate       int    `json:"sample_rate,omitempty" description:"Sample rate, optional values [16000, 24000, 32000, 44100], default 44100."`
+	Bitrate          int    `json:"bitrate,omitempty" description:"Bitrate, optional values [32000, 64000, 128000, 256000], default 256000."`
+	Format           string `json:"format,omitempty" description:"Format, optional values ['mp3', 'wav', 'pcm'], default 'mp3'."`
+}
+
+// ChatCompletionToolRequest 对话补全请求
+type ChatCompletionToolRequest struct {
+	Model        string        `json:"model,omitempty" description:"The model to use. Values range [\"abab5.5-chat\", \"abab5.5s-chat\", \"abab6.5s-chat\", \"MiniMax-Text-01\"], with \"abab5.5-chat\" being the default."`
+	SystemPrompt string        `json:"system_prompt,omitempty" description:"The system prompt placed before the conversation."`
+	Messages     []ChatMessage `json:"messages,omitempty" description:"The previous messages of the conversation, oldest first."`
+	Prompt       string        `json:"prompt,omitempty" description:"The new user message appended after messages. Either prompt or messages must be provided."`
+	Temperature  *float64      `json:"temperature,omitempty" description:"Sampling temperature, range (0, 1]. Higher values make the output more random."`
+	MaxTokens    int           `json:"max_tokens,omitempty" description:"The maximum number of tokens to generate."`
+	Stream       bool          `json:"stream,omitempty" description:"Whether to stream the completion from MiniMax. Useful for long outputs, the combined text is returned either way and, when the call has a progress token, every chunk is sent as a progress notification message. Defaults to False."`
+}
+
+// EmbedTextRequest 文本向量化请求
+type EmbedTextRequest struct {
+	Texts []string `json:"texts" description:"The texts to embed."`
+	Type  string   `json:"type,omitempty" description:"The embedding type. Values range [\"db\", \"query\"], use \"db\" for documents to store and \"query\" for search queries. Defaults to \"db\"." enum:"db,query"`
+	Model string   `json:"model,omitempty" description:"The model to use. Values range [\"embo-01\"], with \"embo-01\" being the default."`
+}
+
+// IndexDocument 待索引文档
+type IndexDocument struct {
+	ID     string `json:"id,omitempty" description:"A unique id of the document. Documents with an existing id are replaced. Derived from the text when not provided."`
+	Text   string `json:"text" description:"The text of the document."`
+	Source string `json:"source,omitempty" description:"Where the document comes from, e.g. a path or URL."`
+}
+
+// IndexDocumentsRequest 文档索引请求
+type IndexDocumentsRequest struct {
+	Documents []IndexDocument `json:"documents,omitempty" description:"The documents to add to the local vector index."`
+	Files     []string        `json:"files,omitempty" description:"Local text files to split into chunks and add to the local vector index."`
+	ChunkSize int             `json:"chunk_size,omitempty" description:"The maximum number of characters per file chunk, default 1000."`
+}
+
+// SearchDocumentsRequest 文档检索请求
+type SearchDocumentsRequest struct {
+	Query string `json:"query" description:"The text to search for."`
+	TopK  int    `json:"top_k,omitempty" description:"The number of documents to return, default 5."`
+}
+
+// ListFilesRequest 列出文件请求
+type ListFilesRequest struct {
+	Purpose string `json:"purpose,omitempty" description:"Only list files uploaded with this purpose. Values range [\"voice_clone\", \"prompt_audio\", \"t2a_async_input\", \"t2a_async\", \"video_generation\"], all purposes are listed when not provided." enum:"voice_clone,prompt_audio,t2a_async_input,t2a_async,video_generation"`
+}
+
+// FileIDRequest 按文件ID操作的请求
+type FileIDRequest struct {
+	FileID string `json:"file_id" description:"The id of the file."`
+}
+
+// DeleteFileRequest 删除文件请求
+type DeleteFileRequest struct {
+	FileID  string `json:"file_id" description:"The id of the file to delete."`
+	Purpose string `json:"purpose,omitempty" description:"The purpose the file was uploaded with, looked up from the file when not provided." enum:"voice_clone,prompt_audio,t2a_async_input,t2a_async,video_generation"`
+}
+
 // GenerateVideoRequest 生成视频请求
 type GenerateVideoRequest struct {
-	Model           string `json:"model,omitempty" description:"The model to use. Values range [\"T2V-01\", \"T2V-01-Director\", \"I2V-01\", \"I2V-01-Director\", \"I2V-01-live\"]. \"Director\" supports inserting instructions for camera movement control. \"I2V\" for image to video. \"T2V\" for text to video."`
-	Prompt          string `json:"prompt" description:"The prompt to generate the video from. When use Director model, the prompt supports 15 Camera Movement Instructions (Enumerated Values)\n            -Truck: [Truck left], [Truck right]\n            -Pan: [Pan left], [Pan right]\n           -Push: [Push in], [Pull out]\n            -Pedestal: [Pedestal up], [Pedestal down]\n            -Tilt: [Tilt up], [Tilt down]\n            -Zoom: [Zoom in], [Zoom out]\n          -Shake: [Shake]\n            -Follow: [Tracking shot]\n            -Static: [Static shot]"`
-	FirstFrameImage string `json:"first_frame_image,omitempty" description:"The first frame image. The model must be \"I2V\" Series."`
+	Model            string `json:"model,omitempty" description:"The model to use. Values range [\"T2V-01\", \"T2V-01-Director\", \"I2V-01\", \"I2V-01-Director\", \"I2V-01-live\", \"S2V-01\", \"MiniMax-Hailuo-02\"]. \"Director\" and \"MiniMax-Hailuo-02\" support inserting instructions for camera movement control. \"I2V\" for image to video. \"T2V\" for text to video. \"S2V\" for subject reference video. Defaults to \"T2V-01\", \"I2V-01\" when first_frame_image is set, or \"S2V-01\" when subject_reference is set."`
+	Prompt           string `json:"prompt" description:"The prompt to generate the video from. When use Director model, the prompt supports 15 Camera Movement Instructions (Enumerated Values)\n            -Truck: [Truck left], [Truck right]\n            -Pan: [Pan left], [Pan right]\n           -Push: [Push in], [Pull out]\n            -Pedestal: [Pedestal up], [Pedestal down]\n            -Tilt: [Tilt up], [Tilt down]\n            -Zoom: [Zoom in], [Zoom out]\n          -Shake: [Shake]\n            -Follow: [Tracking shot]\n            -Static: [Static shot]\n            Several movements can be combined in one bracket, e.g. [Pan left,Pedestal up]."`
+	FirstFrameImage  string `json:"first_frame_image,omitempty" description:"The first frame image as a local file path, an http(s) URL or a data URI. Required by the \"I2V\" series, optional for \"MiniMax-Hailuo-02\". JPG, PNG or WebP, smaller than 20MB, short side at least 300px and aspect ratio between 2:5 and 5:2."`
+	LastFrameImage   string `json:"last_frame_image,omitempty" description:"The last frame image, same format as first_frame_image. Only supported by \"MiniMax-Hailuo-02\"."`
+	SubjectReference string `json:"subject_reference,omitempty" description:"A character reference image for the \"S2V-01\" model, same format as first_frame_image."`
+	Duration         int    `json:"duration,omitempty" description:"Video length in seconds. Values range [6, 10], 10 is only supported by \"MiniMax-Hailuo-02\". Defaults to 6."`
+	Resolution       string `json:"resolution,omitempty" description:"Video resolution. Values range [\"512P\", \"768P\", \"1080P\"] for \"MiniMax-Hailuo-02\", other models only output \"720P\"."`
 }
 
 // TextToImageRequest 文本转图像请求
 type TextToImageRequest struct {
-	Model           string `json:"model,omitempty" description:"The model to use. Values range [\"image-01\"], with \"image-01\" being the default.'"`
-	Prompt          string `json:"prompt,omitempty" description:"The prompt to generate the image from."`
-	AspectRatio     string `json:"aspect_ratio,omitempty" description:"The aspect ratio of the image. Values range [\"1:1\", \"16:9\",\"4:3\", \"3:2\", \"2:3\", \"3:4\", \"9:16\", \"21:9\"], with \"1:1\" being the default."`
-	N               int    `json:"n,omitempty" description:"he number of images to generate. Values range [1, 9], with 1 being the default."`
-	PromptOptimizer bool   `json:"prompt_optimizer,omitempty" description:"Whether to optimize the prompt. Values range [True, False], with True being the default."`
-	ResponseFormat  string `json:"response_format,omitempty" description:"Used to specify the image response format with base64 or url, default url."`
-	OutputDirectory string `json:"output_directory,omitempty" description:"The directory to save the image to, option."`
-}
-
-// RegisterTools Register all tools
-func RegisterTools(s *server.Server, mcp *MCPServer) {
-	// Text-to-speech tool
-	textToAudioTool, err := protocol.NewTool(
-		"text_to_audio",
-		"Convert text to audio with a given voice and save the output audio file to a given directory.\n    Directory is optional, if not provided, the output file will be saved to $HOME/Desktop.\n    Voice id is optional, if not provided, the default voice will be used.\n COST WARNING: This tool makes an API call to Minimax which may incur costs. Only use when explicitly requested by the user.",
-		TextToAudioRequest{},
-	)
-	if err != nil {
-		log.Fatalf("Failed to create text_to_audio tool: %v", err)
+	Model            string `json:"model,omitempty" description:"The model to use. Values range [\"image-01\"], with \"image-01\" being the default.'"`
+	Prompt           string `json:"prompt,omitempty" description:"The prompt to generate the image from."`
+	AspectRatio      string `json:"aspect_ratio,omitempty" description:"The aspect ratio of the image. Values range [\"1:1\", \"16:9\",\"4:3\", \"3:2\", \"2:3\", \"3:4\", \"9:16\", \"21:9\"], with \"1:1\" being the default."`
+	N                int    `json:"n,omitempty" description:"he number of images to generate. Values range [1, 9], with 1 being the default."`
+	PromptOptimizer  bool   `json:"prompt_optimizer,omitempty" description:"Whether to optimize the prompt. Values range [True, False], with True being the default."`
+	ResponseFormat   string `json:"response_format,omitempty" description:"Used to specify the image response format with base64 or url, default url."`
+	OutputDirectory  string `json:"output_directory,omitempty" description:"The directory to save the image to, option."`
+	SubjectReference string `json:"subject_reference,omitempty" description:"A character reference image used to keep the subject consistent across images. Accepts a local file path, an http(s) URL or a data URI of a JPG, PNG or WebP image up to 20MB, with a short side of at least 300px and an aspect ratio between 2:5 and 5:2."`
+	Width            int    `json:"width,omitempty" description:"Custom image width in pixels, range [512, 2048] and divisible by 8. Must be used together with height, aspect_ratio takes priority when both are set."`
+	Height           int    `json:"height,omitempty" description:"Custom image height in pixels, range [512, 2048] and divisible by 8. Must be used together with width."`
+	Seed             int64  `json:"seed,omitempty" description:"Random seed. Using the same seed and parameters produces similar images, random if not provided."`
+	Watermark        bool   `json:"watermark,omitempty" description:"Whether to add the AIGC watermark to the generated images. Defaults to False."`
+}
+
+// ToolGroups maps each group name usable in the tool configuration to its description
+var ToolGroups = map[string]string{
+	"speech":      "text to audio, voice listing and voice cloning",
+	"image":       "image generation",
+	"video":       "video generation",
+	"music":       "music generation",
+	"text":        "chat completion and embeddings",
+	"index":       "local vector index",
+	"files":       "MiniMax files management",
+	"paid":        "tools that incur MiniMax costs",
+	"readonly":    "tools without side effects or costs",
+	"destructive": "tools that delete data",
+}
+
+// ToolConfig selects which tools are registered and overrides their descriptions.
+// Entries of Enable and Disable are tool names or group names prefixed with "@", e.g. "@paid".
+// An empty Enable list enables every tool, Disable is applied afterwards.
+type ToolConfig struct {
+	Enable       []string
+	Disable      []string
+	Descriptions map[string]string
+}
+
+// toolDefinition describes a tool and how it is served
+type toolDefinition struct {
+	name        string
+	description string
+	request     interface{}
+	groups      []string
+	annotations *protocol.ToolAnnotations
+	handler     server.ToolHandlerFunc
+}
+
+// enabled reports whether the configuration allows the tool
+func (c *ToolConfig) enabled(def *toolDefinition) bool {
+	if len(c.Enable) > 0 && !matchesAny(def, c.Enable) {
+		return false
+	}
+	return !matchesAny(def, c.Disable)
+}
+
+// matchesAny reports whether the tool is named by any entry, either directly or through one of its groups
+func matchesAny(def *toolDefinition, entries []string) bool {
+	for _, entry := range entries {
+		if group, ok := strings.CutPrefix(entry, "@"); ok {
+			if slices.Contains(def.groups, group) {
+				return true
+			}
+		} else if entry == def.name {
+			return true
+		}
+	}
+	return false
+}
+
+// warnUnknown logs configuration entries that do not name a known tool or group
+func (c *ToolConfig) warnUnknown(defs []*toolDefinition) {
+	known := func(name string) bool {
+		return slices.ContainsFunc(defs, func(def *toolDefinition) bool { return def.name == name })
 	}
-	s.RegisterTool(textToAudioTool, func(_ context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
-		return mcp.HandleTextToAudio(req)
-	})
-
-	// List available voices tool
-	listVoicesTool, err := protocol.NewTool(
-		"list_voices",
-		"List all voices available. Only supports when api_host is https://api.minimax.chat",
-		ListVoicesRequest{},
-	)
-	if err != nil {
-		log.Fatalf("Failed to create list_voices tool: %v", err)
+	for _, entry := range append(slices.Clone(c.Enable), c.Disable...) {
+		if group, ok := strings.CutPrefix(entry, "@"); ok {
+			if _, exists := ToolGroups[group]; !exists {
+				slog.Warn("Unknown tool group in configuration", "group", entry)
+			}
+		} else if !known(entry) {
+			slog.Warn("Unknown tool in configuration", "tool", entry)
+		}
 	}
-	s.RegisterTool(listVoicesTool, func(_ context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
-		return mcp.HandleListVoices(req)
-	})
-
-	// Voice cloning tool
-	voiceCloneTool, err := protocol.NewTool(
-		"voice_clone",
-		"Clone a voice using provided audio files. The new voice will be charged upon first use. COST WARNING: This tool makes an API call to Minimax which may incur costs. Only use when explicitly requested by the user.",
-		VoiceCloneRequest{},
-	)
-	if err != nil {
-		log.Fatalf("Failed to create voice_clone tool: %v", err)
+	for name := range c.Descriptions {
+		if !known(name) {
+			slog.Warn("Unknown tool in description overrides", "tool", name)
+		}
 	}
-	s.RegisterTool(voiceCloneTool, func(_ context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
-		return mcp.HandleVoiceClone(req)
-	})
-
-	// Generate video tool
-	generateVideoTool, err := protocol.NewTool(
-		"generate_video",
-		"Generate a video from a prompt. COST WARNING: This tool makes an API call to Minimax which may incur costs. Only use when explicitly requested by the user.",
-		GenerateVideoRequest{},
-	)
-	if err != nil {
-		log.Fatalf("Failed to create generate_video tool: %v", err)
+}
+
+// costWarning ends the description of every tool in the paid group. Some of them, like chat_completion,
+// are annotated read-only because they change nothing locally, yet every call is billed.
+const costWarning = "COST WARNING: This tool makes an API call to Minimax which may incur costs. Only use when explicitly requested by the user."
+
+// annotations builds the behaviour hints of a tool, every tool here talks to the MiniMax API
+func annotations(title string, readOnly, destructive, idempotent bool) *protocol.ToolAnnotations {
+	openWorld := true
+	return &protocol.ToolAnnotations{
+		Title:           title,
+		ReadOnlyHint:    &readOnly,
+		DestructiveHint: &destructive,
+		IdempotentHint:  &idempotent,
+		OpenWorldHint:   &openWorld,
 	}
-	s.RegisterTool(generateVideoTool, func(_ context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
-		return mcp.HandleGenerateVideo(req)
-	})
-
-	// Text-to-image tool
-	textToImageTool, err := protocol.NewTool(
-		"text_to_image",
-		"Generate an image from a prompt. COST WARNING: This tool makes an API call to Minimax which may incur costs. Only use when explicitly requested by the user.",
-		TextToImageRequest{},
-	)
-	if err != nil {
-		log.Fatalf("Failed to create text_to_image tool: %v", err)
+}
+
+// toolDefinitions lists every tool the server can provide
+func toolDefinitions(mcp *MCPServer) []*toolDefinition {
+	defs := []*toolDefinition{
+		{
+			name:        "text_to_audio",
+			description: "Convert text to audio with a given voice and save the output audio file to a given directory.\n    Directory is optional, if not provided, the output file will be saved to $HOME/Desktop.\n    Voice id is optional, if not provided, the default voice will be used.\n " + costWarning,
+			request:     TextToAudioRequest{},
+			groups:      []string{"speech", "paid"},
+			annotations: annotations("Text to Audio", false, false, false),
+			handler:     mcp.HandleTextToAudio,
+		},
+		{
+			name:        "list_voices",
+			description: "List all voices available. Only supports when api_host is https://api.minimax.chat",
+			request:     ListVoicesRequest{},
+			groups:      []string{"speech", "readonly"},
+			annotations: annotations("List Voices", true, false, true),
+			handler:     mcp.HandleListVoices,
+		},
+		{
+			name:        "voice_clone",
+			description: "Clone a voice using provided audio files. The new voice will be charged upon first use. " + costWarning,
+			request:     VoiceCloneRequest{},
+			groups:      []string{"speech", "paid"},
+			annotations: annotations("Voice Clone", false, false, false),
+			handler:     mcp.HandleVoiceClone,
+		},
+		{
+			name:        "chat_completion",
+			description: "Ask a MiniMax text model to complete a conversation. Useful for offloading sub-tasks such as translation, summarization or prompt rewriting. " + costWarning,
+			request:     ChatCompletionToolRequest{},
+			groups:      []string{"text", "paid"},
+			annotations: annotations("Chat Completion", true, false, false),
+			handler:     mcp.HandleChatCompletion,
+		},
+		{
+			name:        "embed_text",
+			description: "Convert texts into embedding vectors with a MiniMax embedding model and return them as JSON. " + costWarning,
+			request:     EmbedTextRequest{},
+			groups:      []string{"text", "paid"},
+			annotations: annotations("Embed Text", true, false, true),
+			handler:     mcp.HandleEmbedText,
+		},
+		{
+			name:        "list_files",
+			description: "List the files stored in the MiniMax account, such as uploaded voice clone audio and generated videos.",
+			request:     ListFilesRequest{},
+			groups:      []string{"files", "readonly"},
+			annotations: annotations("List Files", true, false, true),
+			handler:     mcp.HandleListFiles,
+		},
+		{
+			name:        "get_file",
+			description: "Get the metadata and download URL of a file stored in the MiniMax account.",
+			request:     FileIDRequest{},
+			groups:      []string{"files", "readonly"},
+			annotations: annotations("Get File", true, false, true),
+			handler:     mcp.HandleGetFile,
+		},
+		{
+			name:        "download_file",
+			description: "Download a file stored in the MiniMax account. Returns the download URL in url resource mode, otherwise saves the file locally.",
+			request:     FileIDRequest{},
+			groups:      []string{"files"},
+			annotations: annotations("Download File", false, false, true),
+			handler:     mcp.HandleDownloadFile,
+		},
+		{
+			name:        "delete_file",
+			description: "Permanently delete a file stored in the MiniMax account. Only use when explicitly requested by the user.",
+			request:     DeleteFileRequest{},
+			groups:      []string{"files", "destructive"},
+			annotations: annotations("Delete File", false, true, true),
+			handler:     mcp.HandleDeleteFile,
+		},
+		{
+			name:        "generate_music",
+			description: "Generate a song from lyrics and a style prompt, optionally imitating a reference audio, and save the output audio file.\n " + costWarning,
+			request:     GenerateMusicRequest{},
+			groups:      []string{"music", "paid"},
+			annotations: annotations("Generate Music", false, false, false),
+			handler:     mcp.HandleGenerateMusic,
+		},
+		{
+			name:        "generate_video",
+			description: "Generate a video from a prompt, optionally starting from a first frame image, ending at a last frame image or keeping the character of a subject reference image. " + costWarning,
+			request:     GenerateVideoRequest{},
+			groups:      []string{"video", "paid"},
+			annotations: annotations("Generate Video", false, false, false),
+			handler:     mcp.HandleGenerateVideo,
+		},
+		{
+			name:        "text_to_image",
+			description: "Generate an image from a prompt, optionally keeping the character of a subject reference image. " + costWarning,
+			request:     TextToImageRequest{},
+			groups:      []string{"image", "paid"},
+			annotations: annotations("Text to Image", false, false, false),
+			handler:     mcp.HandleTextToImage,
+		},
+	}
+
+	// Local vector index tools, only available when the index is enabled
+	if mcp.Index != nil {
+		defs = append(defs,
+			&toolDefinition{
+				name:        "index_documents",
+				description: "Embed documents or local text files and store them in the local vector index for later retrieval. " + costWarning,
+				request:     IndexDocumentsRequest{},
+				groups:      []string{"index", "paid"},
+				annotations: annotations("Index Documents", false, false, true),
+				handler:     mcp.HandleIndexDocuments,
+			},
+			&toolDefinition{
+				name:        "search_documents",
+				description: "Search the local vector index for the documents most similar to a query by cosine similarity. The query is embedded by the MiniMax API. " + costWarning,
+				request:     SearchDocumentsRequest{},
+				groups:      []string{"index", "paid"},
+				annotations: annotations("Search Documents", true, false, true),
+				handler:     mcp.HandleSearchDocuments,
+			},
+		)
+	}
+
+	return defs
+}
+
+// RegisterTools Register all tools allowed by the configuration
+func RegisterTools(s *server.Server, mcp *MCPServer, cfg ToolConfig) error {
+	defs := toolDefinitions(mcp)
+	cfg.warnUnknown(defs)
+
+	for _, def := range defs {
+		if !cfg.enabled(def) {
+			slog.Info("Tool disabled by configuration", "tool", def.name)
+			continue
+		}
+
+		description := def.description
+		if override, ok := cfg.Descriptions[def.name]; ok && override != "" {
+			description = override
+			// An override cannot hide that a tool costs money
+			if slices.Contains(def.groups, "paid") && !strings.Contains(override, costWarning) {
+				description += " " + costWarning
+			}
+		}
+
+		tool, err := protocol.NewTool(def.name, description, def.request)
+		if err != nil {
+			return fmt.Errorf("failed to create %s tool: %v", def.name, err)
+		}
+		tool.Annotations = def.annotations
+
+		s.RegisterTool(tool, def.handler)
 	}
-	s.RegisterTool(textToImageTool, func(_ context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
-		return mcp.HandleTextToImage(req)
-	})
+	return nil
 }
@@ -0,0 +1,139 @@
+package minimax
+
+import (
+	"context"
+	"mcp/logging"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+)
+
+// sessionIdleTimeout sessions without tool calls for this long are no longer reported as active
+const sessionIdleTimeout = 30 * time.Minute
+
+// Job a tool call in progress
+type Job struct {
+	RequestID string    `json:"request_id"`
+	Tool      string    `json:"tool"`
+	SessionID string    `json:"session_id"`
+	StartedAt time.Time `json:"started_at"`
+	// TaskID is the MiniMax task of asynchronous jobs such as video generation
+	TaskID string `json:"task_id,omitempty"`
+}
+
+// Session tool call activity of an MCP session
+type Session struct {
+	ID         string    `json:"id"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	ToolCalls  int       `json:"tool_calls"`
+	InProgress int       `json:"in_progress"`
+}
+
+// Tracker keeps the running jobs and the recently active sessions for the admin endpoint.
+// The SDK does not expose its session list, so sessions are known from their tool calls.
+type Tracker struct {
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	sessions map[string]*Session
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		jobs:     make(map[string]*Job),
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Middleware registers every tool call as a job while it runs.
+// It relies on RequestLogMiddleware having assigned the request ID.
+func (t *Tracker) Middleware() server.ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+			sessionID, _ := server.GetSessionIDFromCtx(ctx)
+			requestID := logging.RequestID(ctx)
+			t.start(&Job{
+				RequestID: requestID,
+				Tool:      req.Name,
+				SessionID: sessionID,
+				StartedAt: time.Now(),
+			})
+			defer t.finish(requestID, sessionID)
+
+			return next(ctx, req)
+		}
+	}
+}
+
+func (t *Tracker) start(job *Job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.jobs[job.RequestID] = job
+	session, ok := t.sessions[job.SessionID]
+	if !ok {
+		session = &Session{ID: job.SessionID, FirstSeen: job.StartedAt}
+		t.sessions[job.SessionID] = session
+	}
+	session.LastSeen = job.StartedAt
+	session.ToolCalls++
+	session.InProgress++
+}
+
+func (t *Tracker) finish(requestID, sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.jobs, requestID)
+	if session, ok := t.sessions[sessionID]; ok {
+		session.LastSeen = time.Now()
+		session.InProgress--
+	}
+}
+
+// SetTaskID records the MiniMax task of the job running in ctx, it is a no-op on a nil tracker
+func (t *Tracker) SetTaskID(ctx context.Context, taskID string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if job, ok := t.jobs[logging.RequestID(ctx)]; ok {
+		job.TaskID = taskID
+	}
+}
+
+// Jobs returns the running jobs, oldest first
+func (t *Tracker) Jobs() []Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	jobs := make([]Job, 0, len(t.jobs))
+	for _, job := range t.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.Before(jobs[j].StartedAt) })
+	return jobs
+}
+
+// Sessions returns the sessions with a running job or a tool call within the idle timeout, most recent first
+func (t *Tracker) Sessions() []Session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sessions := make([]Session, 0, len(t.sessions))
+	for id, session := range t.sessions {
+		if session.InProgress == 0 && time.Since(session.LastSeen) > sessionIdleTimeout {
+			delete(t.sessions, id)
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeen.After(sessions[j].LastSeen) })
+	return sessions
+}
@@ -0,0 +1,192 @@
+package minimax
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Limits of the image inputs of video generation and subject references
+const (
+	maxImageInputBytes     = 20 * 1024 * 1024
+	minImageInputShortSide = 300
+)
+
+// imageInputFormats the image formats accepted as video frames and subject references, as named by image.DecodeConfig
+var imageInputFormats = []string{"jpeg", "png", "webp"}
+
+// directorCameraMovements are the camera movement instructions accepted by the Director models
+var directorCameraMovements = map[string]struct{}{
+	"Truck left":    {},
+	"Truck right":   {},
+	"Pan left":      {},
+	"Pan right":     {},
+	"Push in":       {},
+	"Pull out":      {},
+	"Pedestal up":   {},
+	"Pedestal down": {},
+	"Tilt up":       {},
+	"Tilt down":     {},
+	"Zoom in":       {},
+	"Zoom out":      {},
+	"Shake":         {},
+	"Tracking shot": {},
+	"Static shot":   {},
+}
+
+var cameraInstructionPattern = regexp.MustCompile(`\[([^\[\]]*)\]`)
+
+// videoModelSpec describes which inputs a video model accepts
+type videoModelSpec struct {
+	firstFrame       string // "required", "optional" or "" when not supported
+	lastFrame        bool
+	subjectReference bool
+	director         bool
+	durations        []int
+	resolutions      []string
+}
+
+var videoModels = map[string]videoModelSpec{
+	"T2V-01":            {durations: []int{6}, resolutions: []string{"720P"}},
+	"T2V-01-Director":   {director: true, durations: []int{6}, resolutions: []string{"720P"}},
+	"I2V-01":            {firstFrame: "required", durations: []int{6}, resolutions: []string{"720P"}},
+	"I2V-01-Director":   {firstFrame: "required", director: true, durations: []int{6}, resolutions: []string{"720P"}},
+	"I2V-01-live":       {firstFrame: "required", durations: []int{6}, resolutions: []string{"720P"}},
+	"S2V-01":            {subjectReference: true, durations: []int{6}, resolutions: []string{"720P"}},
+	"MiniMax-Hailuo-02": {firstFrame: "optional", lastFrame: true, director: true, durations: []int{6, 10}, resolutions: []string{"512P", "768P", "1080P"}},
+}
+
+// validateVideoRequest checks that the inputs are supported by the selected model
+func validateVideoRequest(params *GenerateVideoRequest) error {
+	spec, ok := videoModels[params.Model]
+	if !ok {
+		return fmt.Errorf("unsupported model: %s", params.Model)
+	}
+
+	switch {
+	case spec.firstFrame == "required" && params.FirstFrameImage == "":
+		return fmt.Errorf("the first_frame_image parameter must be provided for model %s", params.Model)
+	case spec.firstFrame == "" && params.FirstFrameImage != "":
+		return fmt.Errorf("model %s does not support first_frame_image", params.Model)
+	case !spec.lastFrame && params.LastFrameImage != "":
+		return fmt.Errorf("model %s does not support last_frame_image", params.Model)
+	case spec.subjectReference && params.SubjectReference == "":
+		return fmt.Errorf("the subject_reference parameter must be provided for model %s", params.Model)
+	case !spec.subjectReference && params.SubjectReference != "":
+		return fmt.Errorf("model %s does not support subject_reference", params.Model)
+	}
+
+	if params.Duration != 0 && !slices.Contains(spec.durations, params.Duration) {
+		return fmt.Errorf("model %s supports durations %v, got %d", params.Model, spec.durations, params.Duration)
+	}
+	if params.Resolution != "" && !slices.Contains(spec.resolutions, params.Resolution) {
+		return fmt.Errorf("model %s supports resolutions %v, got %s", params.Model, spec.resolutions, params.Resolution)
+	}
+
+	if spec.director {
+		return validateCameraInstructions(params.Prompt)
+	}
+	return nil
+}
+
+// validateCameraInstructions checks every bracketed instruction in a Director prompt.
+// A bracket may combine several movements separated by commas, e.g. [Pan left,Pedestal up].
+func validateCameraInstructions(prompt string) error {
+	for _, match := range cameraInstructionPattern.FindAllStringSubmatch(prompt, -1) {
+		for _, movement := range strings.Split(match[1], ",") {
+			movement = strings.TrimSpace(movement)
+			if _, ok := directorCameraMovements[movement]; !ok {
+				return fmt.Errorf("unknown camera movement instruction [%s]", movement)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveValidatedImage resolves an image reference, a video frame or a subject reference, and validates
+// its size, format and dimensions. The size is checked before the image is read. Remote URLs cannot be inspected without downloading
+// and are passed through as is.
+func resolveValidatedImage(ref string) (string, error) {
+	if !isRemoteOrDataURI(ref) {
+		info, err := os.Stat(ref)
+		if err != nil {
+			return "", fmt.Errorf("image file does not exist: %s", ref)
+		}
+		if info.Size() > maxImageInputBytes {
+			return "", fmt.Errorf("image size %d bytes exceeds the 20MB limit", info.Size())
+		}
+	}
+
+	value, err := resolveImageInput(ref)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(value, "data:") {
+		return value, nil
+	}
+
+	// The decoded size of the payload, known before decoding it
+	if idx := strings.Index(value, ","); idx >= 0 {
+		if size := base64.StdEncoding.DecodedLen(len(value) - idx - 1); size > maxImageInputBytes+2 {
+			return "", fmt.Errorf("image size about %d bytes exceeds the 20MB limit", size)
+		}
+	}
+	data, err := decodeDataURI(value)
+	if err != nil {
+		return "", err
+	}
+	if err = validateImageInput(data); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// validateImageInput checks file size, format, short side and aspect ratio of an input image
+func validateImageInput(data []byte) error {
+	if len(data) > maxImageInputBytes {
+		return fmt.Errorf("image size %d bytes exceeds the 20MB limit", len(data))
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err == image.ErrFormat || (err == nil && !slices.Contains(imageInputFormats, format)) {
+		return fmt.Errorf("unsupported image format, use JPG, JPEG, PNG or WebP")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	shortSide, longSide := cfg.Width, cfg.Height
+	if shortSide > longSide {
+		shortSide, longSide = longSide, shortSide
+	}
+	if shortSide < minImageInputShortSide {
+		return fmt.Errorf("%s image is %dx%d, the short side must be at least %dpx", format, cfg.Width, cfg.Height, minImageInputShortSide)
+	}
+	// Aspect ratio must be within 2:5 and 5:2
+	if longSide*2 > shortSide*5 {
+		return fmt.Errorf("%s image is %dx%d, the aspect ratio must be between 2:5 and 5:2", format, cfg.Width, cfg.Height)
+	}
+	return nil
+}
+
+// decodeDataURI returns the payload of a base64 data URI
+func decodeDataURI(uri string) ([]byte, error) {
+	idx := strings.Index(uri, ",")
+	if idx < 0 || !strings.HasSuffix(uri[:idx], ";base64") {
+		return nil, fmt.Errorf("only base64 data URIs are supported")
+	}
+	data, err := base64.StdEncoding.DecodeString(uri[idx+1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data URI: %v", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,251 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"mcp/logging"
+	"mcp/minimax/server/provider"
+	"mcp/minimax/server/tracing"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defaults of the OpenAI-compatible provider
+const (
+	DefaultBaseURL     = "https://api.openai.com/v1"
+	DefaultSpeechModel = "tts-1"
+	DefaultSpeechVoice = "alloy"
+	DefaultImageModel  = "dall-e-3"
+)
+
+// imageSizes the sizes each OpenAI image model accepts, other models of compatible APIs get the requested size
+var imageSizes = map[string][]string{
+	"dall-e-2":    {"256x256", "512x512", "1024x1024"},
+	"dall-e-3":    {"1024x1024", "1792x1024", "1024x1792"},
+	"gpt-image-1": {"1024x1024", "1536x1024", "1024x1536"},
+}
+
+// imagesPerRequest the largest n of image models that do not accept the API maximum of 10
+var imagesPerRequest = map[string]int{"dall-e-3": 1}
+
+// speechFormats audio formats accepted by /audio/speech
+var speechFormats = map[string]bool{"mp3": true, "opus": true, "aac": true, "flac": true, "wav": true, "pcm": true}
+
+// Provider implements the speech and image provider interfaces with an OpenAI-compatible API
+type Provider struct {
+	APIKey string
+	// BaseURL is the API root including the version, DefaultBaseURL when empty
+	BaseURL string
+	// SpeechModel, SpeechVoice and ImageModel are used when the tool call names none
+	SpeechModel string
+	SpeechVoice string
+	ImageModel  string
+	// Transport sends the API requests, http.DefaultTransport when nil
+	Transport http.RoundTripper
+}
+
+// Synthesize converts text to speech with /audio/speech.
+// The API returns the audio itself, so the result never carries a URL.
+func (p *Provider) Synthesize(ctx context.Context, req provider.SpeechRequest) (*provider.Audio, error) {
+	model := firstNonEmpty(req.Model, p.SpeechModel, DefaultSpeechModel)
+	voice := firstNonEmpty(req.VoiceID, p.SpeechVoice, DefaultSpeechVoice)
+	format := req.Format
+	if !speechFormats[format] {
+		return nil, fmt.Errorf("audio format %q is not supported by the openai provider", format)
+	}
+
+	payload := map[string]interface{}{
+		"model":           model,
+		"input":           req.Text,
+		"voice":           voice,
+		"response_format": format,
+	}
+	if req.Speed != 0 {
+		payload["speed"] = req.Speed
+	}
+
+	data, err := p.post(ctx, "/audio/speech", payload, 2*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("API call failed: %v", err)
+	}
+	return &provider.Audio{Data: data, Voice: voice}, nil
+}
+
+// GenerateImages generates images with /images/generations. Models generating fewer images
+// per request than asked for, such as dall-e-3, are called once per batch.
+func (p *Provider) GenerateImages(ctx context.Context, req provider.ImageRequest) (*provider.Images, error) {
+	if req.SubjectReference != "" {
+		return nil, fmt.Errorf("subject_reference is not supported by the openai provider")
+	}
+
+	model := firstNonEmpty(req.Model, p.ImageModel, DefaultImageModel)
+	n := max(req.N, 1)
+	perRequest := n
+	if limit, ok := imagesPerRequest[model]; ok {
+		perRequest = min(n, limit)
+	}
+
+	images := &provider.Images{}
+	for remaining := n; remaining > 0; remaining -= perRequest {
+		payload := map[string]interface{}{
+			"model":  model,
+			"prompt": req.Prompt,
+			"n":      min(perRequest, remaining),
+			"size":   imageSize(model, req),
+		}
+		if req.Base64 {
+			payload["response_format"] = "b64_json"
+		}
+		if err := p.generateImages(ctx, payload, req.Base64, images); err != nil {
+			return nil, err
+		}
+	}
+	return images, nil
+}
+
+// generateImages sends one /images/generations request and appends its images
+func (p *Provider) generateImages(ctx context.Context, payload map[string]interface{}, base64Data bool, images *provider.Images) error {
+	body, err := p.post(ctx, "/images/generations", payload, 3*time.Minute)
+	if err != nil {
+		return fmt.Errorf("image generation API call failed: %v", err)
+	}
+
+	var result struct {
+		Data []struct {
+			URL     string `json:"url"`
+			B64JSON string `json:"b64_json"`
+		} `json:"data"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("response parsing failed: %v", err)
+	}
+
+	// Some models always answer with base64 data, whatever the requested format
+	for _, item := range result.Data {
+		switch {
+		case item.URL != "" && !base64Data:
+			images.URLs = append(images.URLs, item.URL)
+		case item.B64JSON != "":
+			data, err := base64.StdEncoding.DecodeString(item.B64JSON)
+			if err != nil {
+				return fmt.Errorf("failed to decode base64 image: %v", err)
+			}
+			images.Data = append(images.Data, data)
+		}
+	}
+	return nil
+}
+
+// post sends a JSON request and returns the response body, non-200 responses are errors
+func (p *Provider) post(ctx context.Context, endpoint string, payload interface{}, timeout time.Duration) (_ []byte, err error) {
+	ctx, span := tracing.Start(ctx, "OpenAI POST "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", http.MethodPost),
+			attribute.String("url.path", endpoint),
+		))
+	defer func() { tracing.End(span, err) }()
+
+	jsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("JSON encoding failed: %v", err)
+	}
+	baseURL := strings.TrimSuffix(firstNonEmpty(p.BaseURL, DefaultBaseURL), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+endpoint, bytes.NewReader(jsonBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	client := &http.Client{Timeout: timeout, Transport: p.Transport}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	slog.DebugContext(ctx, "OpenAI API request",
+		"endpoint", endpoint, "status", resp.StatusCode, "duration", time.Since(start))
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OpenAI API error (status code: %d): %s", resp.StatusCode, errorMessage(body))
+	}
+	return body, nil
+}
+
+// imageSize maps the requested dimensions or aspect ratio to a size of the model, the one with the
+// nearest aspect ratio and then the nearest area. Models without known sizes get the requested dimensions.
+func imageSize(model string, req provider.ImageRequest) string {
+	width, height := req.Width, req.Height
+	if width == 0 || height == 0 {
+		width, height = 1024, 1024
+		var w, h int
+		if _, err := fmt.Sscanf(req.AspectRatio, "%d:%d", &w, &h); err == nil && w > 0 && h > 0 {
+			width = 1024 * w / h
+		}
+	}
+	sizes, ok := imageSizes[model]
+	if !ok {
+		if req.Width != 0 && req.Height != 0 {
+			return fmt.Sprintf("%dx%d", req.Width, req.Height)
+		}
+		sizes = imageSizes[DefaultImageModel]
+	}
+
+	best, bestRatio, bestArea := "", math.Inf(1), math.Inf(1)
+	for _, size := range sizes {
+		var w, h int
+		fmt.Sscanf(size, "%dx%d", &w, &h)
+		ratio := math.Abs(math.Log(float64(w*height) / float64(h*width)))
+		area := math.Abs(float64(w*h - width*height))
+		// Ratios closer than 1% are equally good, the area decides
+		if ratio < bestRatio-0.01 || (ratio <= bestRatio+0.01 && area < bestArea) {
+			best, bestRatio, bestArea = size, ratio, area
+		}
+	}
+	return best
+}
+
+// errorMessage extracts the message of an OpenAI error response
+func errorMessage(body []byte) string {
+	var result struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.Error.Message != "" {
+		return result.Error.Message
+	}
+	return string(body)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
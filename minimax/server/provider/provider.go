@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+)
+
+// Provider names used in the [Providers] configuration
+const (
+	MiniMax = "minimax"
+	OpenAI  = "openai"
+)
+
+// SpeechRequest text to synthesize and the voice settings of the text_to_audio tool.
+// Providers use the settings they support and ignore the others.
+type SpeechRequest struct {
+	Text string
+	// Model and VoiceID are filled with the provider defaults when empty
+	Model         string
+	VoiceID       string
+	Speed         float64
+	Vol           float64
+	Pitch         int
+	Emotion       string
+	SampleRate    int
+	Bitrate       int
+	Channel       int
+	Format        string
+	LanguageBoost string
+	// URLOutput asks for a download URL instead of the audio data, when the provider can return one
+	URLOutput bool
+}
+
+// Audio synthesized speech, either a URL or the encoded audio
+type Audio struct {
+	URL   string
+	Data  []byte
+	Voice string
+}
+
+// SpeechProvider converts text to speech
+type SpeechProvider interface {
+	Synthesize(ctx context.Context, req SpeechRequest) (*Audio, error)
+}
+
+// ImageRequest parameters of the text_to_image tool
+type ImageRequest struct {
+	Prompt string
+	// Model is filled with the provider default when empty
+	Model       string
+	AspectRatio string
+	Width       int
+	Height      int
+	N           int
+	Seed        int64
+	// PromptOptimizer and Watermark are MiniMax options
+	PromptOptimizer bool
+	Watermark       bool
+	// SubjectReference is an image URL or data URI of the character to keep consistent
+	SubjectReference string
+	// Base64 asks for the image data instead of URLs
+	Base64 bool
+}
+
+// Images generated images, as URLs or as encoded image data
+type Images struct {
+	URLs []string
+	Data [][]byte
+}
+
+// ImageProvider generates images from a prompt
+type ImageProvider interface {
+	GenerateImages(ctx context.Context, req ImageRequest) (*Images, error)
+}
+
+// VideoRequest parameters of the generate_video tool, images are URLs or data URIs
+type VideoRequest struct {
+	Model            string
+	Prompt           string
+	Duration         int
+	Resolution       string
+	FirstFrameImage  string
+	LastFrameImage   string
+	SubjectReference string
+}
+
+// Video task states
+const (
+	VideoPending   = "pending"
+	VideoSucceeded = "succeeded"
+	VideoFailed    = "failed"
+)
+
+// VideoStatus state of a video generation task
+type VideoStatus struct {
+	// State is VideoPending, VideoSucceeded or VideoFailed
+	State string
+	// Status is the status reported by the provider, for logs and traces
+	Status string
+	// URL is the download URL of a succeeded video
+	URL string
+}
+
+// VideoProvider generates videos as asynchronous tasks
+type VideoProvider interface {
+	// SubmitVideo starts a video generation task and returns its ID
+	SubmitVideo(ctx context.Context, req VideoRequest) (string, error)
+	// VideoStatus returns the state of a task
+	VideoStatus(ctx context.Context, taskID string) (*VideoStatus, error)
+}
+
+// VoiceCloneRequest parameters of the voice_clone tool
+type VoiceCloneRequest struct {
+	VoiceID string
+	// File is the local path of the audio sample
+	File string
+	// Text is spoken with the cloned voice in the demo audio
+	Text string
+}
+
+// ClonedVoice result of a voice clone, DemoAudioURL is empty when the provider made no demo
+type ClonedVoice struct {
+	VoiceID      string
+	DemoAudioURL string
+}
+
+// VoiceCloneProvider clones a voice from an audio sample
+type VoiceCloneProvider interface {
+	CloneVoice(ctx context.Context, req VoiceCloneRequest) (*ClonedVoice, error)
+}
+
+// Select returns the provider named name as a T, for the tool that needs it
+func Select[T any](providers map[string]any, tool, name string) (T, error) {
+	var zero T
+	p, ok := providers[name]
+	if !ok {
+		return zero, fmt.Errorf("unknown or unconfigured provider %q for %s", name, tool)
+	}
+	typed, ok := p.(T)
+	if !ok {
+		return zero, fmt.Errorf("provider %q does not support %s", name, tool)
+	}
+	return typed, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"mcp/minimax/server/audit"
+	"mcp/minimax/server/storage"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// auditPath returns the configured audit log path, output/audit.jsonl by default
+func auditPath(cfg *ini.File) string {
+	if path := cfg.Section("Audit").Key("Path").String(); path != "" {
+		return path
+	}
+	return filepath.Join(storage.BuildOutputPath(), "audit.jsonl")
+}
+
+// queryAudit implements the query_audit subcommand: it prints the matching
+// audit records as JSON lines, or a per tool summary, and returns the exit code
+func queryAudit(args []string) int {
+	flags := flag.NewFlagSet("query_audit", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s query_audit [flags]\n\nPrints the audit records matching every given filter as JSON lines.\n\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	var (
+		file    = flags.String("file", "", "audit log to read, rotated files next to it are included (default: [Audit] Path of config.ini)")
+		since   = flags.String("since", "", "only records at or after this time, RFC 3339, YYYY-MM-DD or a duration such as 24h")
+		until   = flags.String("until", "", "only records before this time, same formats as -since")
+		summary = flags.Bool("summary", false, "print calls, outcomes and estimated cost per tool instead of the records")
+		filter  audit.Filter
+	)
+	flags.StringVar(&filter.Tool, "tool", "", "only calls of this tool")
+	flags.StringVar(&filter.Session, "session", "", "only calls of this MCP session")
+	flags.StringVar(&filter.Client, "client", "", "only calls of this client identity")
+	flags.StringVar(&filter.Outcome, "outcome", "", "only calls with this outcome: ok, error or failed")
+	flags.StringVar(&filter.TaskID, "task", "", "only the call of this MiniMax task")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var err error
+	if filter.Since, err = parseAuditTime(*since); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -since: %v\n", err)
+		return 2
+	}
+	if filter.Until, err = parseAuditTime(*until); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -until: %v\n", err)
+		return 2
+	}
+
+	path := *file
+	if path == "" {
+		cfg, err := ini.Load("config.ini")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config file, pass -file: %v\n", err)
+			return 1
+		}
+		path = auditPath(cfg)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	type toolSummary struct {
+		calls    int
+		outcomes map[string]int
+		cost     float64
+	}
+	summaries := make(map[string]*toolSummary)
+
+	err = audit.Query(path, filter, func(record *audit.Record, line []byte) error {
+		if !*summary {
+			out.Write(line)
+			return out.WriteByte('\n')
+		}
+		s, ok := summaries[record.Tool]
+		if !ok {
+			s = &toolSummary{outcomes: make(map[string]int)}
+			summaries[record.Tool] = s
+		}
+		s.calls++
+		s.outcomes[record.Outcome]++
+		s.cost += record.EstimatedCost
+		return nil
+	})
+	if err != nil {
+		out.Flush()
+		fmt.Fprintf(os.Stderr, "Failed to query audit log: %v\n", err)
+		return 1
+	}
+	if !*summary {
+		return 0
+	}
+
+	tools := make([]string, 0, len(summaries))
+	for tool := range summaries {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+
+	var calls int
+	var cost float64
+	fmt.Fprintf(out, "%-24s %8s %8s %8s %8s %12s\n", "TOOL", "CALLS", "OK", "ERROR", "FAILED", "COST")
+	for _, tool := range tools {
+		s := summaries[tool]
+		fmt.Fprintf(out, "%-24s %8d %8d %8d %8d %12.4f\n", tool, s.calls,
+			s.outcomes[audit.OutcomeOK], s.outcomes[audit.OutcomeError], s.outcomes[audit.OutcomeFailed], s.cost)
+		calls += s.calls
+		cost += s.cost
+	}
+	fmt.Fprintf(out, "%-24s %8d %8s %8s %8s %12.4f\n", "TOTAL", calls, "", "", "", cost)
+	return 0
+}
+
+// parseAuditTime parses an RFC 3339 time, a local date or a duration before now
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time, date or duration", value)
+}
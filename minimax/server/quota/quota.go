@@ -0,0 +1,233 @@
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scopes a quota applies to
+const (
+	ScopeSession = "session"
+	ScopeClient  = "client"
+)
+
+// Usage resources consumed by tool calls
+type Usage struct {
+	Calls      int `json:"calls"`
+	Characters int `json:"characters"`
+	Images     int `json:"images"`
+	Videos     int `json:"videos"`
+}
+
+// add returns the sum of two usages
+func (u Usage) add(other Usage) Usage {
+	return Usage{
+		Calls:      u.Calls + other.Calls,
+		Characters: u.Characters + other.Characters,
+		Images:     u.Images + other.Images,
+		Videos:     u.Videos + other.Videos,
+	}
+}
+
+// sub returns u minus other
+func (u Usage) sub(other Usage) Usage {
+	return u.add(Usage{-other.Calls, -other.Characters, -other.Images, -other.Videos})
+}
+
+// Limits maximum usage per window, zero fields are unlimited
+type Limits Usage
+
+// exceeded lists the resources that request would take over the limit, with their current usage
+func (l Limits) exceeded(used, request Usage) []string {
+	var over []string
+	check := func(name string, used, request, limit int) {
+		if limit > 0 && used+request > limit {
+			over = append(over, fmt.Sprintf("%s %d/%d used", name, used, limit))
+		}
+	}
+	check("calls", used.Calls, request.Calls, l.Calls)
+	check("characters", used.Characters, request.Characters, l.Characters)
+	check("images", used.Images, request.Images, l.Images)
+	check("videos", used.Videos, request.Videos, l.Videos)
+	return over
+}
+
+func (l Limits) empty() bool {
+	return l == Limits{}
+}
+
+// Config quota configuration
+type Config struct {
+	// Window is the length of the fixed accounting window
+	Window time.Duration
+	// Session limits each MCP session
+	Session Limits
+	// Client limits each authenticated client across its sessions
+	Client Limits
+	// Path persists the usage across restarts when set
+	Path string
+}
+
+// ExceededError a call rejected because a quota would be exceeded
+type ExceededError struct {
+	Scope    string
+	ID       string
+	Exceeded []string
+	ResetAt  time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("Quota exceeded for %s %s: %s in the current window. The quota resets at %s (in %s), retry after that.",
+		e.Scope, e.ID, strings.Join(e.Exceeded, ", "),
+		e.ResetAt.Format(time.RFC3339), time.Until(e.ResetAt).Round(time.Second))
+}
+
+// counter usage of one scope and id in the current window
+type counter struct {
+	WindowStart time.Time `json:"window_start"`
+	Used        Usage     `json:"used"`
+}
+
+// Manager tracks usage per session and client in fixed windows
+type Manager struct {
+	cfg Config
+
+	mu       sync.Mutex
+	counters map[string]*counter
+}
+
+// Reservation usage taken by a call, released when the call did not consume it
+type Reservation struct {
+	keys  []string
+	usage Usage
+}
+
+// New creates a manager and loads persisted usage, it returns nil when no limit is configured
+func New(cfg Config) (*Manager, error) {
+	if cfg.Session.empty() && cfg.Client.empty() {
+		return nil, nil
+	}
+	if cfg.Window <= 0 {
+		return nil, fmt.Errorf("quota window must be positive")
+	}
+
+	m := &Manager{
+		cfg:      cfg,
+		counters: make(map[string]*counter),
+	}
+	if cfg.Path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(cfg.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quota usage: %v", err)
+	}
+	if err = json.Unmarshal(data, &m.counters); err != nil {
+		return nil, fmt.Errorf("failed to parse quota usage %s: %v", cfg.Path, err)
+	}
+	return m, nil
+}
+
+// Reserve takes usage from the session and client quotas, an empty id skips its scope.
+// Nothing is reserved when either quota would be exceeded, the error is then an *ExceededError.
+func (m *Manager) Reserve(sessionID, clientID string, usage Usage) (*Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type scoped struct {
+		scope, id string
+		limits    Limits
+	}
+	scopes := []scoped{
+		{ScopeSession, sessionID, m.cfg.Session},
+		{ScopeClient, clientID, m.cfg.Client},
+	}
+
+	now := time.Now()
+	reservation := &Reservation{usage: usage}
+	for _, s := range scopes {
+		if s.id == "" || s.limits.empty() {
+			continue
+		}
+		key := s.scope + ":" + s.id
+		c := m.current(key, now)
+		if exceeded := s.limits.exceeded(c.Used, usage); len(exceeded) > 0 {
+			return nil, &ExceededError{
+				Scope:    s.scope,
+				ID:       s.id,
+				Exceeded: exceeded,
+				ResetAt:  c.WindowStart.Add(m.cfg.Window),
+			}
+		}
+		reservation.keys = append(reservation.keys, key)
+	}
+
+	for _, key := range reservation.keys {
+		c := m.counters[key]
+		c.Used = c.Used.add(usage)
+	}
+	m.save()
+	return reservation, nil
+}
+
+// Release returns the usage of a reservation, for calls that failed before consuming anything
+func (m *Manager) Release(reservation *Reservation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range reservation.keys {
+		if c, ok := m.counters[key]; ok {
+			c.Used = c.Used.sub(reservation.usage)
+		}
+	}
+	m.save()
+}
+
+// current returns the counter of key, starting a new window when the previous one ended.
+// Expired counters of other keys are dropped at the same time. The caller holds the lock.
+func (m *Manager) current(key string, now time.Time) *counter {
+	for k, c := range m.counters {
+		if now.Sub(c.WindowStart) >= m.cfg.Window {
+			delete(m.counters, k)
+		}
+	}
+	c, ok := m.counters[key]
+	if !ok {
+		c = &counter{WindowStart: now}
+		m.counters[key] = c
+	}
+	return c
+}
+
+// save persists the counters when a path is configured, the caller holds the lock
+func (m *Manager) save() {
+	if m.cfg.Path == "" {
+		return
+	}
+
+	data, err := json.Marshal(m.counters)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(m.cfg.Path), 0755)
+	}
+	tmp := m.cfg.Path + ".tmp"
+	if err == nil {
+		err = os.WriteFile(tmp, data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, m.cfg.Path)
+	}
+	if err != nil {
+		slog.Error("Failed to persist quota usage", "path", m.cfg.Path, "error", err)
+	}
+}
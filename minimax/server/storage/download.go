@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mcp/minimax/server/metrics"
+	"mcp/minimax/server/tracing"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Default download limits
+const (
+	DefaultDownloadMaxBytes = 512 * 1024 * 1024
+	DefaultDownloadTimeout  = 10 * time.Minute
+	DefaultDownloadRetries  = 3
+)
+
+// ErrDownloadTooLarge is returned when a download exceeds the configured size limit
+var ErrDownloadTooLarge = errors.New("download exceeds size limit")
+
+// DownloadOptions controls the limits applied to a single download
+type DownloadOptions struct {
+	// MaxBytes is the largest accepted file size, 0 means DefaultDownloadMaxBytes
+	MaxBytes int64
+	// Timeout bounds the whole download including retries, 0 means DefaultDownloadTimeout
+	Timeout time.Duration
+	// Retries is the number of resume attempts after a dropped transfer
+	Retries int
+	// Client is the HTTP client used for the transfer, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// DownloadResult describes a completed download
+type DownloadResult struct {
+	Path   string
+	Bytes  int64
+	SHA256 string
+}
+
+func (o DownloadOptions) withDefaults() DownloadOptions {
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultDownloadMaxBytes
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultDownloadTimeout
+	}
+	if o.Retries < 0 {
+		o.Retries = 0
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	return o
+}
+
+// Download streams url into dest without buffering the body in memory.
+// Data is written to a temporary file next to dest, unique to this download,
+// and renamed into place once the size and Content-Length checks pass. A
+// dropped transfer is resumed with an HTTP Range request when the server
+// supports it, and started over when the server answers another range.
+func Download(ctx context.Context, url, dest string, opts DownloadOptions) (*DownloadResult, error) {
+	ctx, span := tracing.Start(ctx, "download", trace.WithAttributes(attribute.String("file.path", dest)))
+	result, err := download(ctx, url, dest, opts)
+	if err != nil {
+		tracing.End(span, err)
+		metrics.Downloads.WithLabelValues(metrics.StatusFailed).Inc()
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int64("file.size", result.Bytes))
+	tracing.End(span, nil)
+
+	metrics.Downloads.WithLabelValues(metrics.StatusOK).Inc()
+	metrics.DownloadedBytes.Add(float64(result.Bytes))
+	return result, nil
+}
+
+func download(ctx context.Context, url, dest string, opts DownloadOptions) (*DownloadResult, error) {
+	opts = opts.withDefaults()
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	// Concurrent downloads of the same dest each get their own file
+	file, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".*.part")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	partPath := file.Name()
+	defer func() {
+		file.Close()
+		os.Remove(partPath)
+	}()
+	if err = file.Chmod(0644); err != nil {
+		return nil, fmt.Errorf("failed to set file mode: %v", err)
+	}
+
+	hasher := sha256.New()
+	var written int64
+	for attempt := 0; ; attempt++ {
+		if err = downloadAttempt(ctx, opts, url, file, hasher, &written); err == nil {
+			break
+		}
+		if errors.Is(err, ErrDownloadTooLarge) || ctx.Err() != nil || attempt >= opts.Retries {
+			return nil, err
+		}
+	}
+
+	if err = file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to flush file: %v", err)
+	}
+	if err = file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close file: %v", err)
+	}
+	if err = os.Rename(partPath, dest); err != nil {
+		return nil, fmt.Errorf("failed to move file into place: %v", err)
+	}
+
+	return &DownloadResult{
+		Path:   dest,
+		Bytes:  written,
+		SHA256: hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+// downloadAttempt performs one GET, resuming from *written when it is non-zero.
+// It returns nil once the full body has been received.
+func downloadAttempt(ctx context.Context, opts DownloadOptions, url string, file *os.File, hasher hash.Hash, written *int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if *written > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", *written))
+	}
+
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server ignored the range or this is the first attempt, start over
+		if *written > 0 {
+			if err = restart(file, hasher, written); err != nil {
+				return err
+			}
+		}
+	case http.StatusPartialContent:
+		if *written == 0 {
+			return fmt.Errorf("unexpected partial content response")
+		}
+		// Data of another range would corrupt the file, the next attempt starts over
+		start, err := contentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil || start != *written {
+			if restartErr := restart(file, hasher, written); restartErr != nil {
+				return restartErr
+			}
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("server resumed at byte %d instead of %d", start, *written)
+		}
+	default:
+		return fmt.Errorf("download failed, status code: %d", resp.StatusCode)
+	}
+
+	// Content-Length covers the remaining bytes for both full and ranged responses
+	expected := int64(-1)
+	if resp.ContentLength >= 0 {
+		expected = *written + resp.ContentLength
+		if expected > opts.MaxBytes {
+			return fmt.Errorf("%w: %d bytes, limit %d", ErrDownloadTooLarge, expected, opts.MaxBytes)
+		}
+	}
+
+	// Read at most one byte past the limit so oversized bodies without Content-Length are detected
+	limited := io.LimitReader(resp.Body, opts.MaxBytes-*written+1)
+	n, copyErr := io.Copy(io.MultiWriter(file, hasher), limited)
+	*written += n
+
+	if *written > opts.MaxBytes {
+		return fmt.Errorf("%w: limit %d", ErrDownloadTooLarge, opts.MaxBytes)
+	}
+	if copyErr != nil {
+		return fmt.Errorf("transfer interrupted after %d bytes: %v", *written, copyErr)
+	}
+	if expected >= 0 && *written != expected {
+		return fmt.Errorf("transfer interrupted: got %d of %d bytes", *written, expected)
+	}
+	return nil
+}
+
+// contentRangeStart returns the first byte of a "bytes start-end/size" Content-Range header
+func contentRangeStart(header string) (int64, error) {
+	rest, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	first, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	return start, nil
+}
+
+// restart discards any partial data so the download can begin again
+func restart(file *os.File, hasher hash.Hash, written *int64) error {
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate file: %v", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind file: %v", err)
+	}
+	hasher.Reset()
+	*written = 0
+	return nil
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testContent is the file served by the test servers
+var testContent = []byte(strings.Repeat("0123456789abcdef", 1024))
+
+// newFlakyServer serves testContent, dropping the connection halfway through the first transfer.
+// Range requests are answered by resume. It returns the server URL and a function listing the Range
+// headers received.
+func newFlakyServer(t *testing.T, resume func(w http.ResponseWriter, start int64)) (string, func() []string) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		first  = true
+		ranges []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		drop := first
+		first = false
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+
+		if drop {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n", len(testContent))
+			buf.Write(testContent[:len(testContent)/2])
+			buf.Flush()
+			conn.Close()
+			return
+		}
+		if value, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes="); ok {
+			start, err := strconv.ParseInt(strings.TrimSuffix(value, "-"), 10, 64)
+			if err != nil {
+				t.Errorf("invalid Range %q", r.Header.Get("Range"))
+				return
+			}
+			resume(w, start)
+			return
+		}
+		w.Write(testContent)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ranges...)
+	}
+}
+
+// writeRange answers a Range request with the content from start, announced as starting at announced
+func writeRange(w http.ResponseWriter, start, announced int64) {
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", announced, len(testContent)-1, len(testContent)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(testContent)-int(start)))
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(testContent[start:])
+}
+
+// checkDownload downloads from the server and verifies the file, its hash and the Range headers sent
+func checkDownload(t *testing.T, url string, ranges func() []string, wantRanges []string) {
+	t.Helper()
+	dest := filepath.Join(t.TempDir(), "file.bin")
+	result, err := Download(context.Background(), url, dest, DownloadOptions{Retries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(testContent) {
+		t.Fatalf("downloaded %d bytes differing from the %d served", len(data), len(testContent))
+	}
+	sum := sha256.Sum256(testContent)
+	if result.Bytes != int64(len(testContent)) || result.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("got %d bytes with hash %s, want %d bytes with hash %x", result.Bytes, result.SHA256, len(testContent), sum)
+	}
+	if got := ranges(); strings.Join(got, ",") != strings.Join(wantRanges, ",") {
+		t.Fatalf("got Range headers %q, want %q", got, wantRanges)
+	}
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d files in the output directory, want only the download", len(entries))
+	}
+}
+
+func TestDownloadResumesPartialContent(t *testing.T) {
+	url, ranges := newFlakyServer(t, func(w http.ResponseWriter, start int64) {
+		writeRange(w, start, start)
+	})
+	half := len(testContent) / 2
+	checkDownload(t, url, ranges, []string{"", fmt.Sprintf("bytes=%d-", half)})
+}
+
+func TestDownloadRestartsOnWrongRange(t *testing.T) {
+	// The server claims to resume at byte 0 while it sends the requested range
+	url, ranges := newFlakyServer(t, func(w http.ResponseWriter, start int64) {
+		writeRange(w, start, 0)
+	})
+	half := len(testContent) / 2
+	checkDownload(t, url, ranges, []string{"", fmt.Sprintf("bytes=%d-", half), ""})
+}
+
+func TestDownloadRestartsWhenRangeIgnored(t *testing.T) {
+	url, ranges := newFlakyServer(t, func(w http.ResponseWriter, start int64) {
+		w.Write(testContent)
+	})
+	half := len(testContent) / 2
+	checkDownload(t, url, ranges, []string{"", fmt.Sprintf("bytes=%d-", half)})
+}
+
+func TestContentRangeStart(t *testing.T) {
+	for _, tt := range []struct {
+		header string
+		start  int64
+		ok     bool
+	}{
+		{"bytes 100-199/200", 100, true},
+		{"bytes 0-9/*", 0, true},
+		{"bytes */200", 0, false},
+		{"items 100-199/200", 0, false},
+		{"", 0, false},
+	} {
+		start, err := contentRangeStart(tt.header)
+		if (err == nil) != tt.ok || start != tt.start {
+			t.Errorf("contentRangeStart(%q) = %d, %v", tt.header, start, err)
+		}
+	}
+}
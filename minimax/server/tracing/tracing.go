@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+const instrumentationName = "mcp/minimax/server"
+
+// Config tracing configuration
+type Config struct {
+	// Exporter is none, otlp or file
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector address such as localhost:4318, empty uses the OTEL_EXPORTER_OTLP_* environment
+	Endpoint string
+	// Insecure disables TLS towards the collector
+	Insecure bool
+	// File receives one JSON span per line with the file exporter
+	File string
+	// SampleRatio is the fraction of new traces recorded, traces started by the client follow its decision
+	SampleRatio float64
+	// ServiceName identifies the server in the tracing backend
+	ServiceName string
+}
+
+// Setup installs the global tracer provider and W3C trace context propagator.
+// The returned function flushes pending spans and must be called before exit.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		cleanup  = func() error { return nil }
+		err      error
+	)
+	switch strings.ToLower(cfg.Exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+		}
+	case ExporterFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("tracing file exporter requires a file path")
+		}
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %v", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %v", err)
+		}
+		cleanup = file.Close
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected none, otlp or file", cfg.Exporter)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "minimax-mcp"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %v", err)
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		if err := provider.Shutdown(ctx); err != nil {
+			return err
+		}
+		return cleanup()
+	}, nil
+}
+
+// Tracer returns the tracer of the server, spans are dropped until Setup installs an exporter
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span with the server tracer
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records err on the span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ExtractMeta returns a context carrying the trace context sent by the client in the
+// MCP request _meta, using the W3C "traceparent" and "tracestate" keys
+func ExtractMeta(ctx context.Context, meta map[string]interface{}) context.Context {
+	if len(meta) == 0 {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{}
+	for key, value := range meta {
+		if s, ok := value.(string); ok {
+			carrier[strings.ToLower(key)] = s
+		}
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
@@ -0,0 +1,49 @@
+package vectorstore
+
+import "strings"
+
+// DefaultChunkSize the default maximum number of characters per chunk
+const DefaultChunkSize = 1000
+
+// Chunk splits text into pieces of at most maxRunes characters, breaking on blank lines where possible
+func Chunk(text string, maxRunes int) []string {
+	if maxRunes <= 0 {
+		maxRunes = DefaultChunkSize
+	}
+
+	var (
+		chunks  []string
+		current strings.Builder
+		size    int
+	)
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+		size = 0
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		runes := []rune(strings.TrimSpace(paragraph))
+		if len(runes) == 0 {
+			continue
+		}
+		if size > 0 && size+len(runes) > maxRunes {
+			flush()
+		}
+		// Paragraphs longer than a chunk are split hard
+		for len(runes) > maxRunes {
+			flush()
+			chunks = append(chunks, string(runes[:maxRunes]))
+			runes = runes[maxRunes:]
+		}
+		if size > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(string(runes))
+		size += len(runes)
+	}
+	flush()
+	return chunks
+}
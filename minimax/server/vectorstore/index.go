@@ -0,0 +1,165 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Document an indexed piece of text and its embedding
+type Document struct {
+	ID     string    `json:"id"`
+	Text   string    `json:"text"`
+	Source string    `json:"source,omitempty"`
+	Vector []float64 `json:"vector"`
+}
+
+// Match a search hit with its cosine similarity to the query
+type Match struct {
+	Document
+	Score float64
+}
+
+// Index an on-disk vector index searched by cosine similarity.
+// The whole index is kept in memory and rewritten atomically on every change.
+type Index struct {
+	path string
+	mu   sync.RWMutex
+	docs map[string]*Document
+}
+
+// Open loads the index stored at path, an empty index is created when the file does not exist
+func Open(path string) (*Index, error) {
+	idx := &Index{
+		path: path,
+		docs: make(map[string]*Document),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %v", err)
+	}
+
+	var docs []*Document
+	if err = json.Unmarshal(data, &docs); err != nil {
+		return nil, fmt.Errorf("failed to parse index %s: %v", path, err)
+	}
+	for _, doc := range docs {
+		idx.docs[doc.ID] = doc
+	}
+	return idx, nil
+}
+
+// Path returns the file backing the index
+func (idx *Index) Path() string {
+	return idx.path
+}
+
+// Len returns the number of indexed documents
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Replace removes every document of the given sources, then adds or replaces docs by ID and persists the index.
+// A file indexed again this way loses the chunks it no longer has.
+func (idx *Index) Replace(sources []string, docs []Document) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for i := range docs {
+		if docs[i].ID == "" {
+			return fmt.Errorf("document %d has no id", i)
+		}
+		if len(docs[i].Vector) == 0 {
+			return fmt.Errorf("document %s has no vector", docs[i].ID)
+		}
+	}
+	if len(sources) > 0 {
+		for id, doc := range idx.docs {
+			if doc.Source != "" && slices.Contains(sources, doc.Source) {
+				delete(idx.docs, id)
+			}
+		}
+	}
+	for i := range docs {
+		doc := docs[i]
+		idx.docs[doc.ID] = &doc
+	}
+	return idx.save()
+}
+
+// Search returns the topK documents most similar to vector
+func (idx *Index) Search(vector []float64, topK int) []Match {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	matches := make([]Match, 0, len(idx.docs))
+	for _, doc := range idx.docs {
+		if len(doc.Vector) != len(vector) {
+			continue
+		}
+		matches = append(matches, Match{Document: *doc, Score: cosine(vector, doc.Vector)})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score == matches[j].Score {
+			return matches[i].ID < matches[j].ID
+		}
+		return matches[i].Score > matches[j].Score
+	})
+	if topK > 0 && len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches
+}
+
+// save writes the index to a temporary file and renames it into place, the caller holds the lock
+func (idx *Index) save() error {
+	docs := make([]*Document, 0, len(idx.docs))
+	for _, doc := range idx.docs {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return fmt.Errorf("failed to encode index: %v", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %v", err)
+	}
+	tmp := idx.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+	if err = os.Rename(tmp, idx.path); err != nil {
+		return fmt.Errorf("failed to move index into place: %v", err)
+	}
+	return nil
+}
+
+// cosine returns the cosine similarity of two vectors of equal length
+func cosine(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}